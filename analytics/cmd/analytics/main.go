@@ -1,25 +1,78 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/hammo/influScope/analytics/internal/metrics"
 	"github.com/hammo/influScope/analytics/internal/service"
 	transport "github.com/hammo/influScope/analytics/internal/transport/grpc"
 )
 
+const (
+	// rulesReloadInterval is how often the ruleset file is checked for changes
+	rulesReloadInterval = 10 * time.Second
+	// shutdownTimeout bounds how long in-flight RPCs get to finish on SIGTERM
+	shutdownTimeout = 15 * time.Second
+)
+
 func main() {
+	reflectionEnabled := flag.Bool("reflection", false, "Expose gRPC server reflection (for grpcurl and similar tools)")
+	flag.Parse()
+
+	// JSON logs, the standard logger is routed through the same handler
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
 	// 1. Initialize Metrics
 	metricsSvc := metrics.NewPrometheusMetrics()
 	go metricsSvc.StartServer(":8084")
 
-	// 2. Initialize Business Logic
-	calculatorSvc := service.NewAnalyticsCalculator()
+	// 2. Initialize Business Logic, with the scoring rules from ANALYTICS_RULES_FILE if set
+	rules := service.DefaultRuleset()
+	rulesFile := os.Getenv("ANALYTICS_RULES_FILE")
+	if rulesFile != "" {
+		var err error
+		if rules, err = service.LoadRuleset(rulesFile); err != nil {
+			log.Fatalf("Failed to load ruleset: %v", err)
+		}
+	}
+	log.Printf("Scoring with ruleset %s", rules.Version)
+
+	calculatorSvc := service.NewAnalyticsCalculator(rules)
+	if rulesFile != "" {
+		go calculatorSvc.WatchRuleset(ctx, rulesFile, rulesReloadInterval)
+	}
 
 	// 3. Initialize and Start gRPC Server
-	grpcServer := transport.NewServer(calculatorSvc, metricsSvc)
+	grpcServer := transport.NewServer(calculatorSvc, calculatorSvc, calculatorSvc, metricsSvc)
+	if *reflectionEnabled {
+		grpcServer.EnableReflection()
+		log.Println("gRPC reflection enabled")
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- grpcServer.Start(":50051") }()
 
-	if err := grpcServer.Start(":50051"); err != nil {
-		log.Fatalf("Failed to serve gRPC: %v", err)
+	// 4. Serve until SIGTERM, then drain in-flight requests
+	select {
+	case err := <-errCh:
+		if err != nil {
+			log.Fatalf("Failed to serve gRPC: %v", err)
+		}
+	case <-ctx.Done():
+		log.Println("Shutting down, finishing in-flight requests...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		grpcServer.Stop(shutdownCtx)
+		log.Println("Analytics service stopped")
 	}
 }
@@ -1,14 +1,102 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // MetricsTracker defines how we track analytics observability
 type MetricsTracker interface {
-	StartTimer() func() // Returns a function to defer stopping the timer
+	// ObserveRPC records the latency and status code of a finished gRPC call
+	ObserveRPC(method, code string, duration time.Duration)
+	// ObserveCalculation records the time taken to score one profile's engagement
+	ObserveCalculation(duration time.Duration)
 	IncEngagementRequest(platform string)
 }
 
-// EngagementCalculator defines the pure business logic contract
+// ProfileStats are the raw numbers an engagement rate is computed from.
+// Likes, Comments, Shares and Views are totals over the last Posts posts.
+type ProfileStats struct {
+	Platform  string
+	Category  string
+	Followers int64
+	Likes     int64
+	Comments  int64
+	Shares    int64
+	Views     int64
+	Posts     int64
+}
+
+// Interactions is the number of engagements the profile received over its last posts
+func (p ProfileStats) Interactions() int64 {
+	return p.Likes + p.Comments + p.Shares
+}
+
+// Engagement is a scored profile along with the ruleset version that scored it
+type Engagement struct {
+	Rate           float64
+	RulesetVersion string
+}
+
+// EngagementCalculator defines the pure business logic contract.
+// The same stats scored with the same ruleset must always produce the same rate.
 type EngagementCalculator interface {
-	Calculate(ctx context.Context, platform string, followers int64) float64
+	Calculate(ctx context.Context, stats ProfileStats) Engagement
+}
+
+// AudienceStats are the signals an audience quality score is computed from
+type AudienceStats struct {
+	Platform         string
+	Category         string
+	Followers        int64
+	Following        int64
+	FollowerHistory  []int64 // Daily follower counts, oldest first
+	PostInteractions []int64 // Interactions on each recent post
+	EngagementRate   float64
+}
+
+// AudienceQuality is a 0 (fake) to 100 (authentic) score and the signals that lowered it
+type AudienceQuality struct {
+	Score          int
+	Reasons        []AudienceReason
+	RulesetVersion string
+}
+
+type AudienceReason struct {
+	Code        string
+	Description string
+	Penalty     int
+}
+
+// AudienceScorer estimates how genuine an account's audience is
+type AudienceScorer interface {
+	ScoreAudience(ctx context.Context, stats AudienceStats) AudienceQuality
+}
+
+// PriceStats are the inputs of a sponsored post price estimate
+type PriceStats struct {
+	Platform       string
+	Category       string
+	Followers      int64
+	EngagementRate float64
+}
+
+// PriceEstimate is the fee range of one content type
+type PriceEstimate struct {
+	ContentType string
+	Low         float64
+	Expected    float64
+	High        float64
+}
+
+// Prices lists an estimate for each content type the platform offers
+type Prices struct {
+	Estimates      []PriceEstimate
+	Currency       string
+	RulesetVersion string
+}
+
+// PriceEstimator prices sponsored content
+type PriceEstimator interface {
+	EstimatePrice(ctx context.Context, stats PriceStats) Prices
 }
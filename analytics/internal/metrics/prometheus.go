@@ -3,14 +3,21 @@ package metrics
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// platformLabels are the platforms counted under their own name. The platform comes from the
+// client, anything else is counted as "other" so a stray value cannot create new series.
+var platformLabels = map[string]bool{"Instagram": true, "TikTok": true, "YouTube": true}
+
 type PrometheusMetrics struct {
 	registry            *prometheus.Registry
 	engagementRequests  *prometheus.CounterVec
+	rpcRequests         *prometheus.CounterVec
+	rpcDuration         *prometheus.HistogramVec
 	calculationDuration prometheus.Histogram
 }
 
@@ -26,28 +33,54 @@ func NewPrometheusMetrics() *PrometheusMetrics {
 			},
 			[]string{"platform"},
 		),
+		rpcRequests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "analytics_grpc_requests_total",
+				Help: "Total number of gRPC calls handled, by method and status code",
+			},
+			[]string{"method", "code"},
+		),
+		rpcDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "analytics_grpc_request_duration_seconds",
+				Help:    "Time taken to handle a gRPC call (a whole stream for streaming methods)",
+				Buckets: prometheus.DefBuckets,
+			},
+			[]string{"method"},
+		),
+		// Deprecated: superseded by analytics_grpc_request_duration_seconds, kept for one
+		// release so dashboards and alerts can move over
 		calculationDuration: prometheus.NewHistogram(
 			prometheus.HistogramOpts{
 				Name:    "analytics_calculation_duration_seconds",
-				Help:    "Time taken to calculate engagement rate",
+				Help:    "Time taken to calculate engagement rate (deprecated, use analytics_grpc_request_duration_seconds)",
 				Buckets: prometheus.DefBuckets,
 			},
 		),
 	}
 
 	reg.MustRegister(pm.engagementRequests)
+	reg.MustRegister(pm.rpcRequests)
+	reg.MustRegister(pm.rpcDuration)
 	reg.MustRegister(pm.calculationDuration)
 
 	return pm
 }
 
-// StartTimer returns a closure that observes the duration when called via defer
-func (m *PrometheusMetrics) StartTimer() func() {
-	timer := prometheus.NewTimer(m.calculationDuration)
-	return func() { timer.ObserveDuration() }
+// ObserveRPC is called by the server interceptors once a call has finished
+func (m *PrometheusMetrics) ObserveRPC(method, code string, duration time.Duration) {
+	m.rpcRequests.WithLabelValues(method, code).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveCalculation(duration time.Duration) {
+	m.calculationDuration.Observe(duration.Seconds())
 }
 
 func (m *PrometheusMetrics) IncEngagementRequest(platform string) {
+	if !platformLabels[platform] {
+		platform = "other"
+	}
 	m.engagementRequests.WithLabelValues(platform).Inc()
 }
 
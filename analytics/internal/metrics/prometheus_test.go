@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUnknownPlatformsShareOneSeries(t *testing.T) {
+	pm := NewPrometheusMetrics()
+
+	pm.IncEngagementRequest("TikTok")
+	pm.IncEngagementRequest("tiktok")
+	pm.IncEngagementRequest("MySpace")
+
+	if n := testutil.CollectAndCount(pm.engagementRequests); n != 2 {
+		t.Errorf("Expected 2 series (TikTok and other), got %d", n)
+	}
+	if v := testutil.ToFloat64(pm.engagementRequests.WithLabelValues("other")); v != 2 {
+		t.Errorf("Expected 2 requests counted as other, got %f", v)
+	}
+}
+
+func TestDeprecatedCalculationDurationIsStillExported(t *testing.T) {
+	pm := NewPrometheusMetrics()
+
+	pm.ObserveCalculation(5 * time.Millisecond)
+
+	if n := testutil.CollectAndCount(pm.registry, "analytics_calculation_duration_seconds"); n != 1 {
+		t.Errorf("Expected analytics_calculation_duration_seconds to be exported, got %d series", n)
+	}
+}
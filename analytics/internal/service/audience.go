@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+)
+
+// Audience quality signals. Each one found takes its penalty off a perfect 100.
+const (
+	// A day-over-day follower gain above this share of the audience looks bought
+	spikeGrowth  = 0.2
+	spikePenalty = 30
+	// Spikes on tiny accounts are normal (a single share can double them)
+	spikeMinFollowers = 1000
+
+	// Engagement under this share of the tier norm suggests followers that never interact
+	lowEngagementRatio   = 0.25
+	lowEngagementPenalty = 35
+
+	// Following more than this share of your audience is typical of follow-for-follow
+	followingRatio   = 1.0
+	followingPenalty = 15
+
+	// A coefficient of variation above this means a few posts get nearly all interactions
+	unevenEngagementCV       = 1.5
+	unevenEngagementPenalty  = 20
+	unevenEngagementMinPosts = 3
+)
+
+// ScoreAudience checks the audience for the usual fake-follower patterns. The tier norm
+// engagement comes from the active ruleset, whose version is returned with the score.
+func (s *AnalyticsCalculator) ScoreAudience(ctx context.Context, stats domain.AudienceStats) domain.AudienceQuality {
+	rules := s.rules.Load()
+	quality := domain.AudienceQuality{Score: 100, RulesetVersion: rules.Version}
+
+	flag := func(code string, penalty int, format string, args ...interface{}) {
+		quality.Reasons = append(quality.Reasons, domain.AudienceReason{
+			Code:        code,
+			Description: fmt.Sprintf(format, args...),
+			Penalty:     penalty,
+		})
+		quality.Score -= penalty
+	}
+
+	// 1. Sudden follower spikes
+	if growth, day := maxDailyGrowth(stats.FollowerHistory); growth > spikeGrowth {
+		flag("follower_spike", spikePenalty, "followers grew %.0f%% in a single day (day %d)", growth*100, day)
+	}
+
+	// 2. Engagement far below what the tier usually gets
+	norm := rules.norm(stats.Platform, stats.Category, stats.Followers)
+	if stats.EngagementRate > 0 && stats.EngagementRate < norm*lowEngagementRatio {
+		flag("low_engagement", lowEngagementPenalty, "engagement rate %.2f%% is far below the %.2f%% norm of %s accounts", stats.EngagementRate, norm, rules.tier(stats.Followers).Name)
+	}
+
+	// 3. Follow-for-follow
+	if stats.Followers > 0 && float64(stats.Following) > float64(stats.Followers)*followingRatio {
+		flag("high_following_ratio", followingPenalty, "follows %d accounts for %d followers", stats.Following, stats.Followers)
+	}
+
+	// 4. Interactions concentrated on a few posts
+	if cv := coefficientOfVariation(stats.PostInteractions); len(stats.PostInteractions) >= unevenEngagementMinPosts && cv > unevenEngagementCV {
+		flag("uneven_engagement", unevenEngagementPenalty, "a few posts get most interactions (coefficient of variation %.1f)", cv)
+	}
+
+	quality.Score = max(quality.Score, 0)
+	return quality
+}
+
+// maxDailyGrowth returns the largest relative day-over-day gain and the day it happened,
+// ignoring days where the account was still too small for growth to mean anything
+func maxDailyGrowth(history []int64) (float64, int) {
+	best, day := 0.0, 0
+	for i := 1; i < len(history); i++ {
+		prev := history[i-1]
+		if prev < spikeMinFollowers {
+			continue
+		}
+		if growth := float64(history[i]-prev) / float64(prev); growth > best {
+			best, day = growth, i
+		}
+	}
+	return best, day
+}
+
+// coefficientOfVariation is the standard deviation relative to the mean, 0 for an empty or all-zero series
+func coefficientOfVariation(values []int64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+
+	var variance float64
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return math.Sqrt(variance/float64(len(values))) / mean
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+)
+
+func TestScoreAudience(t *testing.T) {
+	// A steadily growing account with a healthy rate for a macro Instagram profile (norm 3%)
+	genuine := domain.AudienceStats{
+		Platform:         "Instagram",
+		Followers:        200000,
+		Following:        800,
+		FollowerHistory:  []int64{190000, 192000, 195000, 197500, 200000},
+		PostInteractions: []int64{6000, 5400, 7100, 6300, 5900},
+		EngagementRate:   3.1,
+	}
+
+	tests := []struct {
+		name      string
+		stats     func(s domain.AudienceStats) domain.AudienceStats
+		wantScore int
+		wantCodes []string
+	}{
+		{
+			name:      "Genuine audience",
+			stats:     func(s domain.AudienceStats) domain.AudienceStats { return s },
+			wantScore: 100,
+		},
+		{
+			name: "Sudden follower spike",
+			stats: func(s domain.AudienceStats) domain.AudienceStats {
+				s.FollowerHistory = []int64{100000, 101000, 150000, 200000}
+				return s
+			},
+			wantScore: 70,
+			wantCodes: []string{"follower_spike"},
+		},
+		{
+			name: "Spikes on tiny accounts are ignored",
+			stats: func(s domain.AudienceStats) domain.AudienceStats {
+				s.FollowerHistory = []int64{200, 900, 5000}
+				return s
+			},
+			wantScore: 100,
+		},
+		{
+			name: "Engagement far below the tier norm",
+			stats: func(s domain.AudienceStats) domain.AudienceStats {
+				s.EngagementRate = 0.5
+				return s
+			},
+			wantScore: 65,
+			wantCodes: []string{"low_engagement"},
+		},
+		{
+			name: "Unknown engagement is not penalised",
+			stats: func(s domain.AudienceStats) domain.AudienceStats {
+				s.EngagementRate = 0
+				return s
+			},
+			wantScore: 100,
+		},
+		{
+			name: "Bought account with every signal",
+			stats: func(s domain.AudienceStats) domain.AudienceStats {
+				s.Following = 250000
+				s.FollowerHistory = []int64{20000, 200000}
+				s.PostInteractions = []int64{0, 10, 0, 5, 40000}
+				s.EngagementRate = 0.1
+				return s
+			},
+			wantScore: 0,
+			wantCodes: []string{"follower_spike", "low_engagement", "high_following_ratio", "uneven_engagement"},
+		},
+	}
+
+	calc := NewAnalyticsCalculator(DefaultRuleset())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calc.ScoreAudience(context.Background(), tt.stats(genuine))
+
+			if got.Score != tt.wantScore {
+				t.Errorf("Expected score %d, got %d (%+v)", tt.wantScore, got.Score, got.Reasons)
+			}
+			if len(got.Reasons) != len(tt.wantCodes) {
+				t.Fatalf("Expected reasons %v, got %+v", tt.wantCodes, got.Reasons)
+			}
+			for i, code := range tt.wantCodes {
+				if got.Reasons[i].Code != code || got.Reasons[i].Description == "" {
+					t.Errorf("Reason %d: expected %s with a description, got %+v", i, code, got.Reasons[i])
+				}
+			}
+			if got.RulesetVersion != DefaultRuleset().Version {
+				t.Errorf("Expected the active ruleset version, got %q", got.RulesetVersion)
+			}
+		})
+	}
+}
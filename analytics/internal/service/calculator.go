@@ -2,26 +2,64 @@ package service
 
 import (
 	"context"
-	"math/rand"
+	"math"
+	"sync/atomic"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
 )
 
-type AnalyticsCalculator struct{}
+type AnalyticsCalculator struct {
+	// Swapped as a whole on reload, so a score never mixes two rulesets
+	rules atomic.Pointer[Ruleset]
+}
+
+func NewAnalyticsCalculator(rules *Ruleset) *AnalyticsCalculator {
+	calc := &AnalyticsCalculator{}
+	calc.SetRuleset(rules)
+	return calc
+}
+
+// Ruleset returns the active ruleset
+func (s *AnalyticsCalculator) Ruleset() *Ruleset {
+	return s.rules.Load()
+}
 
-func NewAnalyticsCalculator() *AnalyticsCalculator {
-	return &AnalyticsCalculator{}
+// SetRuleset replaces the active ruleset, it must already be validated
+func (s *AnalyticsCalculator) SetRuleset(rules *Ruleset) {
+	s.rules.Store(rules)
 }
 
-func (s *AnalyticsCalculator) Calculate(ctx context.Context, platform string, followers int64) float64 {
-	baseRate := 3.0
-	if platform == "TikTok" {
-		baseRate = 6.0
+// Calculate returns the engagement rate as a percentage. Video platforms are usually measured
+// per view, since their reach is driven by recommendations rather than followers; Instagram
+// (and anything unknown) is measured per follower and per post. The ruleset decides which.
+func (s *AnalyticsCalculator) Calculate(ctx context.Context, stats domain.ProfileStats) domain.Engagement {
+	rules := s.rules.Load()
+	return domain.Engagement{
+		Rate:           rate(rules, stats),
+		RulesetVersion: rules.Version,
 	}
+}
+
+func rate(rules *Ruleset, stats domain.ProfileStats) float64 {
+	platform := rules.platform(stats.Platform)
 
-	followerFactor := 1.0
-	if followers > 1000000 {
-		followerFactor = 0.5 // Big accounts have lower engagement
+	switch platform.Metric {
+	case MetricPerView:
+		if stats.Views > 0 {
+			return clampRate(100 * float64(stats.Interactions()) / float64(stats.Views))
+		}
+	case MetricPerFollower:
+		if stats.Followers > 0 && stats.Posts > 0 {
+			perPost := float64(stats.Interactions()) / float64(stats.Posts)
+			return clampRate(100 * perPost / float64(stats.Followers))
+		}
 	}
 
-	// Add some randomness
-	return (baseRate * followerFactor) + (rand.Float64() * 2.0)
+	// No activity data, estimate from the platform, audience size and category
+	return clampRate(rules.norm(stats.Platform, stats.Category, stats.Followers))
+}
+
+// clampRate rounds to two decimals and keeps inconsistent inputs within 0-100%
+func clampRate(rate float64) float64 {
+	return math.Round(math.Min(math.Max(rate, 0), 100)*100) / 100
 }
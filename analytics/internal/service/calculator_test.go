@@ -3,70 +3,118 @@ package service
 import (
 	"context"
 	"testing"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
 )
 
 func TestCalculateEngagement(t *testing.T) {
 	tests := []struct {
-		name      string
-		platform  string
-		followers int64
-		wantMin   float64
-		wantMax   float64
+		name  string
+		stats domain.ProfileStats
+		want  float64
 	}{
 		{
-			name:      "TikTok user over 1M followers",
-			platform:  "TikTok",
-			followers: 2000000,
-			wantMin:   3.0,
-			wantMax:   8.0,
+			name:  "Instagram is measured per follower and per post",
+			stats: domain.ProfileStats{Platform: "Instagram", Followers: 10000, Likes: 4000, Comments: 500, Shares: 500, Posts: 10},
+			want:  5.0, // 500 interactions per post / 10k followers
+		},
+		{
+			name:  "TikTok is measured per view",
+			stats: domain.ProfileStats{Platform: "TikTok", Followers: 10000, Likes: 9000, Comments: 600, Shares: 400, Views: 200000, Posts: 10},
+			want:  5.0,
+		},
+		{
+			name:  "YouTube is measured per view regardless of followers",
+			stats: domain.ProfileStats{Platform: "YouTube", Followers: 0, Likes: 300, Comments: 50, Views: 10000, Posts: 5},
+			want:  3.5,
+		},
+		{
+			name:  "Unknown platforms are measured per follower",
+			stats: domain.ProfileStats{Platform: "Unknown", Followers: 3000, Likes: 200, Posts: 2},
+			want:  3.33,
 		},
 		{
-			name:      "TikTok user under 1M followers",
-			platform:  "TikTok",
-			followers: 100000,
-			wantMin:   6.0,
-			wantMax:   8.0,
+			name:  "Inconsistent counts are capped",
+			stats: domain.ProfileStats{Platform: "TikTok", Likes: 500, Views: 100},
+			want:  100,
 		},
 		{
-			name:      "Instagram user over 1M followers",
-			platform:  "Instagram",
-			followers: 1500000,
-			wantMin:   1.5,
-			wantMax:   5.0,
+			name:  "TikTok without views falls back to the baseline",
+			stats: domain.ProfileStats{Platform: "TikTok", Followers: 100000},
+			want:  6.0,
 		},
 		{
-			name:      "Instagram user below 1M followers",
-			platform:  "Instagram",
-			followers: 500000,
-			wantMin:   3.0,
-			wantMax:   5.0,
+			name:  "Big TikTok accounts have a lower baseline",
+			stats: domain.ProfileStats{Platform: "TikTok", Followers: 2000000},
+			want:  3.0,
 		},
 		{
-			name:      "Edge case with zero followers",
-			platform:  "YouTube",
-			followers: 0,
-			wantMin:   3.0,
-			wantMax:   5.0,
+			name:  "Instagram without posts falls back to the baseline",
+			stats: domain.ProfileStats{Platform: "Instagram", Followers: 1500000, Likes: 1000},
+			want:  1.5,
 		},
 		{
-			name:      "Unsupported platform",
-			platform:  "Unknown",
-			followers: 1000,
-			wantMin:   3.0,
-			wantMax:   5.0,
+			name:  "Edge case with zero followers",
+			stats: domain.ProfileStats{Platform: "YouTube", Followers: 0},
+			want:  3.0,
 		},
 	}
 
-	calc := NewAnalyticsCalculator()
+	calc := NewAnalyticsCalculator(DefaultRuleset())
 	ctx := context.Background()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			rate := calc.Calculate(ctx, tt.platform, tt.followers)
-
-			if rate < tt.wantMin || rate > tt.wantMax {
-				t.Errorf("Calculate() EngagementRate = %v, want between %v and %v", rate, tt.wantMin, tt.wantMax)
+			if rate := calc.Calculate(ctx, tt.stats).Rate; rate != tt.want {
+				t.Errorf("Calculate() EngagementRate = %v, want %v", rate, tt.want)
 			}
 		})
 	}
 }
+
+func TestCalculateEngagementIsReproducible(t *testing.T) {
+	calc := NewAnalyticsCalculator(DefaultRuleset())
+	stats := domain.ProfileStats{Platform: "Instagram", Followers: 48213, Likes: 12034, Comments: 871, Shares: 93, Posts: 12}
+
+	first := calc.Calculate(context.Background(), stats)
+	for i := 0; i < 100; i++ {
+		if rate := calc.Calculate(context.Background(), stats); rate != first {
+			t.Fatalf("Run %d returned %v, first run returned %v", i, rate, first)
+		}
+	}
+}
+
+func TestCalculateEngagementFollowsRuleset(t *testing.T) {
+	rules := &Ruleset{
+		Version: "test-1",
+		Platforms: map[string]PlatformRules{
+			"TikTok": {Metric: MetricPerFollower, Baseline: 4.0},
+		},
+		DefaultPlatform: PlatformRules{Metric: MetricPerView, Baseline: 2.0},
+		Tiers: []Tier{
+			{Name: "small", MaxFollowers: 1000, Multiplier: 2.0},
+			{Name: "large", Multiplier: 0.5},
+		},
+		Categories: map[string]float64{"Gaming": 1.5},
+	}
+	calc := NewAnalyticsCalculator(rules)
+	ctx := context.Background()
+
+	// TikTok is switched to per follower: 50 interactions per post / 1000 followers
+	got := calc.Calculate(ctx, domain.ProfileStats{Platform: "TikTok", Followers: 1000, Likes: 100, Views: 1, Posts: 2})
+	if got.Rate != 5.0 || got.RulesetVersion != "test-1" {
+		t.Errorf("Expected 5.0 scored by test-1, got %+v", got)
+	}
+
+	// Baseline 4.0, small tier x2, Gaming x1.5
+	got = calc.Calculate(ctx, domain.ProfileStats{Platform: "TikTok", Category: "Gaming", Followers: 1000})
+	if got.Rate != 12.0 {
+		t.Errorf("Expected a 12.0 baseline, got %v", got.Rate)
+	}
+
+	// Unknown platform uses the default baseline, large tier x0.5, unlisted category x1
+	got = calc.Calculate(ctx, domain.ProfileStats{Platform: "Twitch", Category: "Food", Followers: 1001})
+	if got.Rate != 1.0 {
+		t.Errorf("Expected a 1.0 baseline, got %v", got.Rate)
+	}
+}
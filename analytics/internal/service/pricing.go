@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+)
+
+// An engagement rate far from the tier norm moves the price, within these bounds
+const (
+	minEngagementFactor = 0.5
+	maxEngagementFactor = 2.0
+)
+
+// EstimatePrice prices a sponsored piece of content for every type the platform offers:
+// the followers it reaches times the category CPM, scaled by how the profile engages
+// compared to its tier. Platforms without content types get no estimates.
+func (s *AnalyticsCalculator) EstimatePrice(ctx context.Context, stats domain.PriceStats) domain.Prices {
+	rules := s.rules.Load()
+	pricing := rules.Pricing
+	prices := domain.Prices{Currency: pricing.Currency, RulesetVersion: rules.Version}
+
+	cpm := pricing.DefaultCPM
+	if c, ok := pricing.CategoryCPM[stats.Category]; ok {
+		cpm = c
+	}
+
+	// Unknown engagement is priced as average
+	factor := 1.0
+	if stats.EngagementRate > 0 {
+		norm := rules.norm(stats.Platform, stats.Category, stats.Followers)
+		factor = math.Min(math.Max(stats.EngagementRate/norm, minEngagementFactor), maxEngagementFactor)
+	}
+
+	types := pricing.ContentTypes[stats.Platform]
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Map order is random, responses should not be
+
+	for _, name := range names {
+		impressions := float64(stats.Followers) * types[name]
+		expected := impressions / 1000 * cpm * factor
+		prices.Estimates = append(prices.Estimates, domain.PriceEstimate{
+			ContentType: name,
+			Low:         math.Round(expected * (1 - pricing.Spread)),
+			Expected:    math.Round(expected),
+			High:        math.Round(expected * (1 + pricing.Spread)),
+		})
+	}
+	return prices
+}
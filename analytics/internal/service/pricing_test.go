@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+)
+
+func TestEstimatePrice(t *testing.T) {
+	calc := NewAnalyticsCalculator(DefaultRuleset())
+	ctx := context.Background()
+
+	// 100k Tech followers on Instagram at the 3% norm: a post reaches 25k at a $14 CPM
+	prices := calc.EstimatePrice(ctx, domain.PriceStats{Platform: "Instagram", Category: "Tech", Followers: 100000, EngagementRate: 3.0})
+
+	want := []domain.PriceEstimate{
+		{ContentType: "post", Low: 245, Expected: 350, High: 455},
+		{ContentType: "reel", Low: 343, Expected: 490, High: 637},
+		{ContentType: "story", Low: 98, Expected: 140, High: 182},
+	}
+	if len(prices.Estimates) != len(want) {
+		t.Fatalf("Expected %d estimates, got %+v", len(want), prices.Estimates)
+	}
+	for i := range want {
+		if prices.Estimates[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], prices.Estimates[i])
+		}
+	}
+	if prices.Currency != "USD" || prices.RulesetVersion != DefaultRuleset().Version {
+		t.Errorf("Expected USD prices from the active ruleset, got %s / %s", prices.Currency, prices.RulesetVersion)
+	}
+}
+
+func TestEstimatePriceFollowsEngagement(t *testing.T) {
+	calc := NewAnalyticsCalculator(DefaultRuleset())
+	ctx := context.Background()
+	expected := func(rate float64) float64 {
+		prices := calc.EstimatePrice(ctx, domain.PriceStats{Platform: "TikTok", Category: "Unlisted", Followers: 50000, EngagementRate: rate})
+		if len(prices.Estimates) != 1 || prices.Estimates[0].ContentType != "video" {
+			t.Fatalf("Expected a single video estimate on TikTok, got %+v", prices.Estimates)
+		}
+		return prices.Estimates[0].Expected
+	}
+
+	// 20k views at the $10 default CPM, the TikTok norm is 6%
+	if got := expected(6.0); got != 200 {
+		t.Errorf("Expected 200 at the norm, got %v", got)
+	}
+	if got := expected(0); got != 200 {
+		t.Errorf("Expected unknown engagement to be priced at the norm, got %v", got)
+	}
+	if got := expected(9.0); got != 300 {
+		t.Errorf("Expected 1.5x the price for 1.5x the norm, got %v", got)
+	}
+	if got := expected(60.0); got != 400 {
+		t.Errorf("Expected the engagement premium to be capped at 2x, got %v", got)
+	}
+	if got := expected(0.1); got != 100 {
+		t.Errorf("Expected the engagement discount to be capped at 0.5x, got %v", got)
+	}
+}
+
+func TestEstimatePriceUnknownPlatform(t *testing.T) {
+	calc := NewAnalyticsCalculator(DefaultRuleset())
+	prices := calc.EstimatePrice(context.Background(), domain.PriceStats{Platform: "Twitch", Followers: 1000})
+	if len(prices.Estimates) != 0 {
+		t.Errorf("Expected no estimates for a platform without content types, got %+v", prices.Estimates)
+	}
+}
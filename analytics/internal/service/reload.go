@@ -0,0 +1,50 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// WatchRuleset polls the ruleset file and swaps in new versions until ctx is cancelled.
+// Polling the content rather than relying on file events also catches the symlink swap
+// Kubernetes does when a mounted ConfigMap changes. An invalid file is logged and ignored,
+// the calculator keeps scoring with the last valid ruleset.
+func (s *AnalyticsCalculator) WatchRuleset(ctx context.Context, path string, interval time.Duration) {
+	last, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Failed to read ruleset %s: %v", path, err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read ruleset %s: %v", path, err)
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		// Remember the content even when it is invalid so the error is only logged once
+		last = data
+
+		rules, err := ParseRuleset(data, path)
+		if err != nil {
+			log.Printf("Keeping ruleset %s: %v", s.Ruleset().Version, err)
+			continue
+		}
+		s.SetRuleset(rules)
+		log.Printf("Loaded ruleset %s from %s", rules.Version, path)
+	}
+}
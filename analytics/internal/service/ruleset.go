@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// defaultRuleset is the ruleset the service runs with when no file is configured
+//
+//go:embed rules/default.yaml
+var defaultRuleset []byte
+
+// Metrics a platform can be measured with
+const (
+	MetricPerView     = "per_view"
+	MetricPerFollower = "per_follower"
+)
+
+// Ruleset holds the tunable parts of the engagement, audience and pricing models
+type Ruleset struct {
+	Version         string                   `yaml:"version" json:"version"`
+	Platforms       map[string]PlatformRules `yaml:"platforms" json:"platforms"`
+	DefaultPlatform PlatformRules            `yaml:"default_platform" json:"default_platform"`
+	Tiers           []Tier                   `yaml:"tiers" json:"tiers"`
+	Categories      map[string]float64       `yaml:"categories" json:"categories"`
+	Pricing         Pricing                  `yaml:"pricing" json:"pricing"`
+}
+
+type PlatformRules struct {
+	Metric   string  `yaml:"metric" json:"metric"`
+	Baseline float64 `yaml:"baseline" json:"baseline"` // Rate (in %) assumed without activity data
+}
+
+// Tier is a follower bracket, MaxFollowers is inclusive and 0 on the unbounded last tier
+type Tier struct {
+	Name         string  `yaml:"name" json:"name"`
+	MaxFollowers int64   `yaml:"max_followers" json:"max_followers"`
+	Multiplier   float64 `yaml:"multiplier" json:"multiplier"`
+}
+
+// Pricing holds the CPM tables sponsored posts are priced with
+type Pricing struct {
+	Currency    string             `yaml:"currency" json:"currency"`
+	DefaultCPM  float64            `yaml:"default_cpm" json:"default_cpm"`
+	CategoryCPM map[string]float64 `yaml:"category_cpm" json:"category_cpm"`
+	// Spread is the share of the expected price taken off for low and added for high
+	Spread float64 `yaml:"spread" json:"spread"`
+	// ContentTypes lists, per platform, the share of followers each content type reaches
+	ContentTypes map[string]map[string]float64 `yaml:"content_types" json:"content_types"`
+}
+
+// contentTypes are the formats a price can be estimated for, each one has its own
+// field in the influencers index
+var contentTypes = map[string]bool{"post": true, "story": true, "reel": true, "video": true}
+
+// DefaultRuleset returns the embedded ruleset
+func DefaultRuleset() *Ruleset {
+	rules, err := ParseRuleset(defaultRuleset, "default.yaml")
+	if err != nil {
+		panic(fmt.Sprintf("embedded ruleset is invalid: %v", err))
+	}
+	return rules
+}
+
+// LoadRuleset reads and validates a YAML or JSON ruleset file
+func LoadRuleset(path string) (*Ruleset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseRuleset(data, path)
+}
+
+// ParseRuleset decodes a ruleset, picking JSON or YAML from the file name. Unknown keys
+// are rejected so a typo does not silently fall back to a zero value.
+func ParseRuleset(data []byte, name string) (*Ruleset, error) {
+	var rules Ruleset
+	var err error
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&rules)
+	} else {
+		err = yaml.UnmarshalStrict(data, &rules)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding ruleset %s: %w", name, err)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ruleset %s: %w", name, err)
+	}
+	return &rules, nil
+}
+
+// Validate reports every problem of the ruleset at once
+func (r *Ruleset) Validate() error {
+	var errs []error
+	if r.Version == "" {
+		errs = append(errs, errors.New("version is required"))
+	}
+
+	for name, p := range r.Platforms {
+		if err := p.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("platform %s: %w", name, err))
+		}
+	}
+	if err := r.DefaultPlatform.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("default_platform: %w", err))
+	}
+
+	if len(r.Tiers) == 0 {
+		errs = append(errs, errors.New("at least one tier is required"))
+	}
+	for i, t := range r.Tiers {
+		last := i == len(r.Tiers)-1
+		switch {
+		case t.Name == "":
+			errs = append(errs, fmt.Errorf("tier %d: name is required", i))
+		case t.Multiplier <= 0:
+			errs = append(errs, fmt.Errorf("tier %s: multiplier must be positive", t.Name))
+		case last && t.MaxFollowers != 0:
+			errs = append(errs, fmt.Errorf("tier %s: the last tier must not have max_followers", t.Name))
+		case !last && t.MaxFollowers <= 0:
+			errs = append(errs, fmt.Errorf("tier %s: max_followers must be positive", t.Name))
+		case i > 0 && !last && t.MaxFollowers <= r.Tiers[i-1].MaxFollowers:
+			errs = append(errs, fmt.Errorf("tier %s: max_followers must be greater than the previous tier's", t.Name))
+		}
+	}
+
+	for name, m := range r.Categories {
+		if m <= 0 {
+			errs = append(errs, fmt.Errorf("category %s: multiplier must be positive", name))
+		}
+	}
+
+	if err := r.Pricing.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("pricing: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+func (p Pricing) validate() error {
+	var errs []error
+	if p.Currency == "" {
+		errs = append(errs, errors.New("currency is required"))
+	}
+	if p.DefaultCPM <= 0 {
+		errs = append(errs, errors.New("default_cpm must be positive"))
+	}
+	for name, cpm := range p.CategoryCPM {
+		if cpm <= 0 {
+			errs = append(errs, fmt.Errorf("category_cpm %s must be positive", name))
+		}
+	}
+	if p.Spread < 0 || p.Spread >= 1 {
+		errs = append(errs, fmt.Errorf("spread must be within [0, 1), got %v", p.Spread))
+	}
+	if len(p.ContentTypes) == 0 {
+		errs = append(errs, errors.New("content_types is required"))
+	}
+	for platform, types := range p.ContentTypes {
+		for name, reach := range types {
+			if !contentTypes[name] {
+				errs = append(errs, fmt.Errorf("content type %s of %s is not one of post, story, reel, video", name, platform))
+			}
+			if reach <= 0 || reach > 1 {
+				errs = append(errs, fmt.Errorf("reach of %s on %s must be within (0, 1], got %v", name, platform, reach))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p PlatformRules) validate() error {
+	if p.Metric != MetricPerView && p.Metric != MetricPerFollower {
+		return fmt.Errorf("metric must be %s or %s, got %q", MetricPerView, MetricPerFollower, p.Metric)
+	}
+	if p.Baseline <= 0 || p.Baseline > 100 {
+		return fmt.Errorf("baseline must be within (0, 100], got %v", p.Baseline)
+	}
+	return nil
+}
+
+// platform returns the rules of a platform, or the defaults when it is not listed
+func (r *Ruleset) platform(name string) PlatformRules {
+	if p, ok := r.Platforms[name]; ok {
+		return p
+	}
+	return r.DefaultPlatform
+}
+
+// tier returns the tier covering the follower count
+func (r *Ruleset) tier(followers int64) Tier {
+	for _, t := range r.Tiers[:len(r.Tiers)-1] {
+		if followers <= t.MaxFollowers {
+			return t
+		}
+	}
+	return r.Tiers[len(r.Tiers)-1]
+}
+
+// norm is the engagement rate (in %) expected from a profile: the platform baseline scaled
+// by its follower tier and category
+func (r *Ruleset) norm(platform, category string, followers int64) float64 {
+	return r.platform(platform).Baseline * r.tier(followers).Multiplier * r.categoryMultiplier(category)
+}
+
+// categoryMultiplier returns the category adjustment, 1 when the category is not listed
+func (r *Ruleset) categoryMultiplier(category string) float64 {
+	if m, ok := r.Categories[category]; ok {
+		return m
+	}
+	return 1
+}
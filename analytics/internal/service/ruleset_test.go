@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+const validRuleset = `
+version: "v1"
+platforms:
+  TikTok: { metric: per_view, baseline: 6.0 }
+default_platform: { metric: per_follower, baseline: 3.0 }
+tiers:
+  - { name: small, max_followers: 10000, multiplier: 1.0 }
+  - { name: big, multiplier: 0.5 }
+pricing:
+  currency: USD
+  default_cpm: 10
+  spread: 0.2
+  content_types:
+    TikTok: { video: 0.4 }
+`
+
+func TestDefaultRulesetIsValid(t *testing.T) {
+	rules := DefaultRuleset()
+	if rules.Version == "" || len(rules.Tiers) == 0 {
+		t.Errorf("Expected a complete default ruleset, got %+v", rules)
+	}
+}
+
+func TestDeployedRulesetMatchesDefault(t *testing.T) {
+	// The ConfigMap shipped with the manifests carries its own copy of the embedded rules
+	raw, err := os.ReadFile(filepath.Join("..", "..", "..", "k8s", "apps", "analytics-rules.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read the ConfigMap: %v", err)
+	}
+	var configMap struct {
+		Data map[string]string `yaml:"data"`
+	}
+	if err := yaml.Unmarshal(raw, &configMap); err != nil {
+		t.Fatalf("Failed to decode the ConfigMap: %v", err)
+	}
+
+	deployed, err := ParseRuleset([]byte(configMap.Data["rules.yaml"]), "rules.yaml")
+	if err != nil {
+		t.Fatalf("The deployed ruleset is invalid: %v", err)
+	}
+	if !reflect.DeepEqual(deployed, DefaultRuleset()) {
+		t.Errorf("k8s/apps/analytics-rules.yaml and rules/default.yaml differ, update both (version %q vs %q)", deployed.Version, DefaultRuleset().Version)
+	}
+}
+
+func TestParseRuleset(t *testing.T) {
+	yamlRules, err := ParseRuleset([]byte(validRuleset), "rules.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	jsonRules, err := ParseRuleset([]byte(`{
+		"version": "v1",
+		"platforms": {"TikTok": {"metric": "per_view", "baseline": 6.0}},
+		"default_platform": {"metric": "per_follower", "baseline": 3.0},
+		"tiers": [{"name": "small", "max_followers": 10000, "multiplier": 1.0}, {"name": "big", "multiplier": 0.5}],
+		"pricing": {"currency": "USD", "default_cpm": 10, "spread": 0.2, "content_types": {"TikTok": {"video": 0.4}}}
+	}`), "rules.json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, rules := range []*Ruleset{yamlRules, jsonRules} {
+		if rules.tier(10000).Name != "small" || rules.tier(10001).Name != "big" {
+			t.Errorf("Tier boundaries are not inclusive: %+v", rules.Tiers)
+		}
+		if rules.platform("TikTok").Baseline != 6.0 || rules.platform("Twitch").Baseline != 3.0 {
+			t.Errorf("Unexpected platform rules: %+v", rules)
+		}
+	}
+}
+
+func TestParseRulesetRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr string
+	}{
+		{
+			name:    "Unknown key",
+			rules:   validRuleset + "baselines: {}\n",
+			wantErr: "baselines",
+		},
+		{
+			name:    "Missing version",
+			rules:   strings.Replace(validRuleset, `version: "v1"`, "", 1),
+			wantErr: "version is required",
+		},
+		{
+			name:    "Unknown metric",
+			rules:   strings.Replace(validRuleset, "metric: per_view", "metric: per_like", 1),
+			wantErr: "platform TikTok: metric",
+		},
+		{
+			name:    "Baseline out of range",
+			rules:   strings.Replace(validRuleset, "baseline: 6.0", "baseline: 0", 1),
+			wantErr: "baseline",
+		},
+		{
+			name:    "Bounded last tier",
+			rules:   strings.Replace(validRuleset, "name: big,", "name: big, max_followers: 20000,", 1),
+			wantErr: "tier big: the last tier",
+		},
+		{
+			name:    "Unknown content type",
+			rules:   strings.Replace(validRuleset, "video: 0.4", "carousel: 0.4", 1),
+			wantErr: "content type carousel",
+		},
+		{
+			name:    "Spread out of range",
+			rules:   strings.Replace(validRuleset, "spread: 0.2", "spread: 1.5", 1),
+			wantErr: "pricing: spread",
+		},
+		{
+			name:    "Negative category multiplier",
+			rules:   validRuleset + "categories: { Tech: -1 }\n",
+			wantErr: "category Tech",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRuleset([]byte(tt.rules), "rules.yaml")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWatchRulesetReloadsOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write ruleset: %v", err)
+		}
+	}
+	write(validRuleset)
+
+	rules, err := LoadRuleset(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	calc := NewAnalyticsCalculator(rules)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go calc.WatchRuleset(ctx, path, 10*time.Millisecond)
+
+	waitForVersion := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for calc.Ruleset().Version != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected ruleset %s, still on %s", want, calc.Ruleset().Version)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// An invalid edit is ignored...
+	write(strings.Replace(validRuleset, `version: "v1"`, `version: "v2"`+"\nunknown: true", 1))
+	time.Sleep(50 * time.Millisecond)
+	waitForVersion("v1")
+
+	// ...and the next valid one is picked up
+	write(strings.Replace(validRuleset, `version: "v1"`, `version: "v3"`, 1))
+	waitForVersion("v3")
+}
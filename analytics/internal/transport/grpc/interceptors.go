@@ -0,0 +1,124 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// unaryInterceptors give every unary RPC observability and safety nets, outermost first:
+// logging and metrics see the final status, including panics recovered further in.
+func unaryInterceptors(logger *slog.Logger, metrics domain.MetricsTracker) []grpc.UnaryServerInterceptor {
+	return []grpc.UnaryServerInterceptor{
+		observeUnary(logger, metrics),
+		recoverUnary(logger),
+		deadlineUnary,
+	}
+}
+
+// streamInterceptors are the streaming counterparts, applied over the whole stream
+func streamInterceptors(logger *slog.Logger, metrics domain.MetricsTracker) []grpc.StreamServerInterceptor {
+	return []grpc.StreamServerInterceptor{
+		observeStream(logger, metrics),
+		recoverStream(logger),
+		deadlineStream,
+	}
+}
+
+// observeUnary records latency and status code per method and logs one line per call
+func observeUnary(logger *slog.Logger, metrics domain.MetricsTracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(ctx, logger, metrics, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func observeStream(logger *slog.Logger, metrics domain.MetricsTracker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observe(ss.Context(), logger, metrics, info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observe(ctx context.Context, logger *slog.Logger, metrics domain.MetricsTracker, method string, start time.Time, err error) {
+	duration := time.Since(start)
+	code := status.Code(err)
+	metrics.ObserveRPC(method, code.String(), duration)
+
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+	}
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", duration),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	logger.LogAttrs(ctx, level, "grpc request", attrs...)
+}
+
+// recoverUnary turns a panic into codes.Internal instead of crashing the whole service
+func recoverUnary(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer recoverPanic(ctx, logger, info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+func recoverStream(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(ss.Context(), logger, info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(ctx context.Context, logger *slog.Logger, method string, err *error) {
+	if r := recover(); r != nil {
+		logger.ErrorContext(ctx, "grpc handler panicked",
+			slog.String("method", method),
+			slog.Any("panic", r),
+			slog.String("stack", string(debug.Stack())),
+		)
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// deadlineUnary rejects calls whose deadline already passed (e.g. spent waiting in a queue),
+// the caller has given up on the answer so computing it is wasted work
+func deadlineUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := checkDeadline(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func deadlineStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := checkDeadline(ss.Context()); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func checkDeadline(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded before the request was handled")
+	}
+	return nil
+}
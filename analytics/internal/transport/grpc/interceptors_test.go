@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+	pb "github.com/hammo/influScope/gen/analytics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// panickingCalculator blows up on profiles without followers
+type panickingCalculator struct{}
+
+func (panickingCalculator) Calculate(ctx context.Context, stats domain.ProfileStats) domain.Engagement {
+	if stats.Followers == 0 {
+		panic("division by zero")
+	}
+	return domain.Engagement{Rate: 1}
+}
+
+func TestPanicsBecomeInternalErrors(t *testing.T) {
+	metrics := &mockMetrics{}
+	client := newTestClient(t, NewServer(panickingCalculator{}, mockScorer{}, mockPricer{}, metrics))
+
+	_, err := client.CalculateEngagement(context.Background(), &pb.EngagementRequest{Username: "boom"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected codes.Internal, got %v", err)
+	}
+
+	// Streams are protected too
+	stream, err := client.StreamEngagement(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stream.Send(&pb.EngagementRequest{Username: "boom"})
+	if _, err := stream.Recv(); status.Code(err) != codes.Internal {
+		t.Fatalf("Expected codes.Internal on the stream, got %v", err)
+	}
+
+	// The server survived
+	if _, err := client.CalculateEngagement(context.Background(), &pb.EngagementRequest{Followers: 10}); err != nil {
+		t.Fatalf("Expected the server to keep serving, got %v", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	want := []string{
+		"/analytics.AnalyticsService/CalculateEngagement Internal",
+		"/analytics.AnalyticsService/StreamEngagement Internal",
+		"/analytics.AnalyticsService/CalculateEngagement OK",
+	}
+	if len(metrics.rpcs) != len(want) {
+		t.Fatalf("Expected %v to be observed, got %v", want, metrics.rpcs)
+	}
+	for i := range want {
+		if metrics.rpcs[i] != want[i] {
+			t.Errorf("Call %d: expected %q, got %q", i, want[i], metrics.rpcs[i])
+		}
+	}
+}
+
+func TestExpiredDeadlinesAreRejected(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := deadlineUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Expected codes.DeadlineExceeded, got %v", err)
+	}
+	if called {
+		t.Error("Expected the handler not to run")
+	}
+
+	// A live deadline goes through
+	ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := deadlineUnary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test"}, handler); err != nil || !called {
+		t.Errorf("Expected the handler to run, got %v", err)
+	}
+}
+
+func TestRequestsAreLoggedStructurally(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	interceptor := observeUnary(logger, &mockMetrics{})
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad platform")
+	}
+	interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/analytics.AnalyticsService/EstimatePrice"}, handler)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q", buf.String())
+	}
+	want := map[string]any{
+		"level":  "WARN",
+		"method": "/analytics.AnalyticsService/EstimatePrice",
+		"code":   "InvalidArgument",
+		"error":  "bad platform",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["duration"]; !ok {
+		t.Error("Expected the duration to be logged")
+	}
+}
@@ -2,41 +2,215 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log"
+	"log/slog"
 	"net"
+	"time"
 
 	"github.com/hammo/influScope/analytics/internal/domain"
 	pb "github.com/hammo/influScope/gen/analytics"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
+// MaxBatchSize caps the requests of one batch call, larger batches are rejected with
+// InvalidArgument so a single call cannot hold the server for long. Keep the proto comments in sync.
+const MaxBatchSize = 500
+
 type Server struct {
 	pb.UnimplementedAnalyticsServiceServer
 	calculator domain.EngagementCalculator
+	audience   domain.AudienceScorer
+	pricer     domain.PriceEstimator
 	metrics    domain.MetricsTracker
+
+	grpcServer *grpc.Server
+	health     *health.Server
 }
 
-func NewServer(calc domain.EngagementCalculator, metrics domain.MetricsTracker) *Server {
-	return &Server{
+func NewServer(calc domain.EngagementCalculator, audience domain.AudienceScorer, pricer domain.PriceEstimator, metrics domain.MetricsTracker) *Server {
+	s := &Server{
 		calculator: calc,
+		audience:   audience,
+		pricer:     pricer,
 		metrics:    metrics,
+		grpcServer: grpc.NewServer(
+			grpc.ChainUnaryInterceptor(unaryInterceptors(slog.Default(), metrics)...),
+			grpc.ChainStreamInterceptor(streamInterceptors(slog.Default(), metrics)...),
+		),
+		health: health.NewServer(),
 	}
+	pb.RegisterAnalyticsServiceServer(s.grpcServer, s)
+	healthpb.RegisterHealthServer(s.grpcServer, s.health)
+
+	// Not ready until Serve runs
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	s.health.SetServingStatus(pb.AnalyticsService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	return s
+}
+
+// EnableReflection exposes the service descriptors to tools like grpcurl. Call it before Start.
+func (s *Server) EnableReflection() {
+	reflection.Register(s.grpcServer)
 }
 
 func (s *Server) CalculateEngagement(ctx context.Context, req *pb.EngagementRequest) (*pb.EngagementResponse, error) {
-	// 1. Start Metrics
-	stopTimer := s.metrics.StartTimer()
-	defer stopTimer()
+	return s.calculate(ctx, req), nil
+}
+
+// CalculateEngagementBatch scores every request of the batch, responses[i] answers requests[i]
+func (s *Server) CalculateEngagementBatch(ctx context.Context, req *pb.EngagementBatchRequest) (*pb.EngagementBatchResponse, error) {
+	if err := checkBatch(len(req.Requests)); err != nil {
+		return nil, err
+	}
+	resp := &pb.EngagementBatchResponse{
+		Responses: make([]*pb.EngagementResponse, 0, len(req.Requests)),
+	}
+	for _, r := range req.Requests {
+		resp.Responses = append(resp.Responses, s.calculate(ctx, r))
+	}
+	return resp, nil
+}
+
+// checkBatch rejects batches over MaxBatchSize
+func checkBatch(n int) error {
+	if n > MaxBatchSize {
+		return status.Errorf(codes.InvalidArgument, "batch of %d requests exceeds the limit of %d", n, MaxBatchSize)
+	}
+	return nil
+}
+
+// StreamEngagement answers each request as it arrives until the client closes its side
+func (s *Server) StreamEngagement(stream pb.AnalyticsService_StreamEngagementServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(s.calculate(stream.Context(), req)); err != nil {
+			return err
+		}
+	}
+}
+
+// calculate scores a single profile, whichever RPC it came from
+func (s *Server) calculate(ctx context.Context, req *pb.EngagementRequest) *pb.EngagementResponse {
+	// 1. Count per platform, call latency is recorded by the interceptors
 	s.metrics.IncEngagementRequest(req.Platform)
+	start := time.Now()
 
 	// 2. Delegate to Business Logic
-	rate := s.calculator.Calculate(ctx, req.Platform, req.Followers)
-	log.Printf("Engagement Rate for %s on %s is %.2f\n", req.Username, req.Platform, rate)
+	engagement := s.calculator.Calculate(ctx, domain.ProfileStats{
+		Platform:  req.Platform,
+		Category:  req.Category,
+		Followers: req.Followers,
+		Likes:     req.Likes,
+		Comments:  req.Comments,
+		Shares:    req.Shares,
+		Views:     req.Views,
+		Posts:     req.Posts,
+	})
+	s.metrics.ObserveCalculation(time.Since(start))
+	log.Printf("Engagement Rate for %s on %s is %.2f (rules %s)\n", req.Username, req.Platform, engagement.Rate, engagement.RulesetVersion)
 
 	// 3. Return Protobuf Response
 	return &pb.EngagementResponse{
-		EngagementRate: rate,
-	}, nil
+		EngagementRate: engagement.Rate,
+		RulesetVersion: engagement.RulesetVersion,
+	}
+}
+
+func (s *Server) ScoreAudienceQuality(ctx context.Context, req *pb.AudienceQualityRequest) (*pb.AudienceQualityResponse, error) {
+	return s.scoreAudience(ctx, req), nil
+}
+
+// ScoreAudienceQualityBatch scores every request of the batch, responses[i] answers requests[i]
+func (s *Server) ScoreAudienceQualityBatch(ctx context.Context, req *pb.AudienceQualityBatchRequest) (*pb.AudienceQualityBatchResponse, error) {
+	if err := checkBatch(len(req.Requests)); err != nil {
+		return nil, err
+	}
+	resp := &pb.AudienceQualityBatchResponse{
+		Responses: make([]*pb.AudienceQualityResponse, 0, len(req.Requests)),
+	}
+	for _, r := range req.Requests {
+		resp.Responses = append(resp.Responses, s.scoreAudience(ctx, r))
+	}
+	return resp, nil
+}
+
+func (s *Server) scoreAudience(ctx context.Context, req *pb.AudienceQualityRequest) *pb.AudienceQualityResponse {
+	quality := s.audience.ScoreAudience(ctx, domain.AudienceStats{
+		Platform:         req.Platform,
+		Category:         req.Category,
+		Followers:        req.Followers,
+		Following:        req.Following,
+		FollowerHistory:  req.FollowerHistory,
+		PostInteractions: req.PostInteractions,
+		EngagementRate:   req.EngagementRate,
+	})
+
+	resp := &pb.AudienceQualityResponse{
+		Score:          int32(quality.Score),
+		RulesetVersion: quality.RulesetVersion,
+	}
+	for _, r := range quality.Reasons {
+		resp.Reasons = append(resp.Reasons, &pb.AudienceQualityReason{
+			Code:        r.Code,
+			Description: r.Description,
+			Penalty:     int32(r.Penalty),
+		})
+	}
+	return resp
+}
+
+func (s *Server) EstimatePrice(ctx context.Context, req *pb.PriceRequest) (*pb.PriceResponse, error) {
+	return s.estimatePrice(ctx, req), nil
+}
+
+// EstimatePriceBatch prices every request of the batch, responses[i] answers requests[i]
+func (s *Server) EstimatePriceBatch(ctx context.Context, req *pb.PriceBatchRequest) (*pb.PriceBatchResponse, error) {
+	if err := checkBatch(len(req.Requests)); err != nil {
+		return nil, err
+	}
+	resp := &pb.PriceBatchResponse{
+		Responses: make([]*pb.PriceResponse, 0, len(req.Requests)),
+	}
+	for _, r := range req.Requests {
+		resp.Responses = append(resp.Responses, s.estimatePrice(ctx, r))
+	}
+	return resp, nil
+}
+
+func (s *Server) estimatePrice(ctx context.Context, req *pb.PriceRequest) *pb.PriceResponse {
+	prices := s.pricer.EstimatePrice(ctx, domain.PriceStats{
+		Platform:       req.Platform,
+		Category:       req.Category,
+		Followers:      req.Followers,
+		EngagementRate: req.EngagementRate,
+	})
+
+	resp := &pb.PriceResponse{
+		Currency:       prices.Currency,
+		RulesetVersion: prices.RulesetVersion,
+	}
+	for _, e := range prices.Estimates {
+		resp.Estimates = append(resp.Estimates, &pb.PriceEstimate{
+			ContentType: e.ContentType,
+			Low:         e.Low,
+			Expected:    e.Expected,
+			High:        e.High,
+		})
+	}
+	return resp
 }
 
 func (s *Server) Start(port string) error {
@@ -45,9 +219,38 @@ func (s *Server) Start(port string) error {
 		return err
 	}
 
-	grpcServer := grpc.NewServer()
-	pb.RegisterAnalyticsServiceServer(grpcServer, s)
-
 	log.Printf("Analytics Service (gRPC) running on %s", port)
-	return grpcServer.Serve(lis)
+	return s.Serve(lis)
+}
+
+// Serve accepts connections on lis and reports the service as healthy until Stop is called.
+// It returns nil once the server has been stopped.
+func (s *Server) Serve(lis net.Listener) error {
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(pb.AnalyticsService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	if err := s.grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop reports NOT_SERVING so clients and probes move away, then lets in-flight RPCs and open
+// streams finish. Whatever is still running when ctx expires is cut off.
+func (s *Server) Stop(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("Graceful stop timed out, closing remaining connections")
+		s.grpcServer.Stop()
+		<-done
+	}
 }
@@ -0,0 +1,396 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/analytics/internal/domain"
+	pb "github.com/hammo/influScope/gen/analytics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// --- MOCKS ---
+
+// mockCalculator returns a rate derived from the followers so responses can be matched to requests
+type mockCalculator struct{}
+
+func (mockCalculator) Calculate(ctx context.Context, stats domain.ProfileStats) domain.Engagement {
+	return domain.Engagement{Rate: float64(stats.Followers) / 1000, RulesetVersion: "test"}
+}
+
+// mockScorer flags every profile following more accounts than it has followers
+type mockScorer struct{}
+
+func (mockScorer) ScoreAudience(ctx context.Context, stats domain.AudienceStats) domain.AudienceQuality {
+	if stats.Following > stats.Followers {
+		return domain.AudienceQuality{Score: 60, RulesetVersion: "test", Reasons: []domain.AudienceReason{
+			{Code: "high_following_ratio", Description: "follows too many accounts", Penalty: 40},
+		}}
+	}
+	return domain.AudienceQuality{Score: 100, RulesetVersion: "test"}
+}
+
+// mockPricer prices a post at a dollar per follower
+type mockPricer struct{}
+
+func (mockPricer) EstimatePrice(ctx context.Context, stats domain.PriceStats) domain.Prices {
+	p := float64(stats.Followers)
+	return domain.Prices{Currency: "USD", RulesetVersion: "test", Estimates: []domain.PriceEstimate{
+		{ContentType: "post", Low: p / 2, Expected: p, High: p * 2},
+	}}
+}
+
+type mockMetrics struct {
+	mu       sync.Mutex
+	requests int
+	rpcs     []string // "method code" of every observed call
+}
+
+func (m *mockMetrics) ObserveRPC(method, code string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rpcs = append(m.rpcs, method+" "+code)
+}
+func (m *mockMetrics) ObserveCalculation(duration time.Duration) {}
+func (m *mockMetrics) IncEngagementRequest(platform string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+}
+
+// newTestClient serves s over an in-memory listener
+func newTestClient(t *testing.T, s *Server) pb.AnalyticsServiceClient {
+	return pb.NewAnalyticsServiceClient(newTestConn(t, s))
+}
+
+func newTestConn(t *testing.T, s *Server) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	go s.Serve(lis)
+	t.Cleanup(s.grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newMockServer() *Server {
+	return NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, &mockMetrics{})
+}
+
+// --- TESTS ---
+
+func TestCalculateEngagementBatch(t *testing.T) {
+	metrics := &mockMetrics{}
+	client := newTestClient(t, NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, metrics))
+
+	resp, err := client.CalculateEngagementBatch(context.Background(), &pb.EngagementBatchRequest{
+		Requests: []*pb.EngagementRequest{
+			{Username: "a", Followers: 1000, Platform: "TikTok"},
+			{Username: "b", Followers: 5000, Platform: "Instagram"},
+			{Username: "c", Followers: 2000, Platform: "YouTube"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Responses come back in request order
+	want := []float64{1, 5, 2}
+	if len(resp.Responses) != len(want) {
+		t.Fatalf("Expected %d responses, got %d", len(want), len(resp.Responses))
+	}
+	for i, r := range resp.Responses {
+		if r.EngagementRate != want[i] {
+			t.Errorf("Response %d: expected %.1f, got %.1f", i, want[i], r.EngagementRate)
+		}
+		if r.RulesetVersion != "test" {
+			t.Errorf("Response %d: expected the ruleset version, got %q", i, r.RulesetVersion)
+		}
+	}
+	if metrics.requests != 3 {
+		t.Errorf("Expected every profile to be counted, got %d", metrics.requests)
+	}
+}
+
+func TestStreamEngagement(t *testing.T) {
+	client := newTestClient(t, NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, &mockMetrics{}))
+
+	stream, err := client.StreamEngagement(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, followers := range []int64{3000, 7000} {
+		if err := stream.Send(&pb.EngagementRequest{Username: "u", Followers: followers}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if resp.EngagementRate != float64(followers)/1000 {
+			t.Errorf("Expected %.1f, got %.1f", float64(followers)/1000, resp.EngagementRate)
+		}
+	}
+
+	// Closing our side ends the stream cleanly
+	if err := stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected io.EOF after CloseSend, got %v", err)
+	}
+}
+
+// recordingCalculator keeps the stats it was asked to score
+type recordingCalculator struct{ got []domain.ProfileStats }
+
+func (r *recordingCalculator) Calculate(ctx context.Context, stats domain.ProfileStats) domain.Engagement {
+	r.got = append(r.got, stats)
+	return domain.Engagement{}
+}
+
+func TestCalculateEngagementForwardsActivity(t *testing.T) {
+	calc := &recordingCalculator{}
+	client := newTestClient(t, NewServer(calc, mockScorer{}, mockPricer{}, &mockMetrics{}))
+
+	_, err := client.CalculateEngagement(context.Background(), &pb.EngagementRequest{
+		Username: "u", Platform: "TikTok", Category: "Tech", Followers: 10, Likes: 1, Comments: 2, Shares: 3, Views: 4, Posts: 5,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := domain.ProfileStats{Platform: "TikTok", Category: "Tech", Followers: 10, Likes: 1, Comments: 2, Shares: 3, Views: 4, Posts: 5}
+	if len(calc.got) != 1 || calc.got[0] != want {
+		t.Errorf("Expected calculator to receive %+v, got %+v", want, calc.got)
+	}
+}
+
+func TestScoreAudienceQualityBatch(t *testing.T) {
+	client := newTestClient(t, NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, &mockMetrics{}))
+
+	resp, err := client.ScoreAudienceQualityBatch(context.Background(), &pb.AudienceQualityBatchRequest{
+		Requests: []*pb.AudienceQualityRequest{
+			{Username: "genuine", Followers: 1000, Following: 10},
+			{Username: "f4f", Followers: 1000, Following: 5000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(resp.Responses))
+	}
+
+	if r := resp.Responses[0]; r.Score != 100 || len(r.Reasons) != 0 {
+		t.Errorf("Expected a clean 100 for the first profile, got %v", r)
+	}
+	r := resp.Responses[1]
+	if r.Score != 60 || len(r.Reasons) != 1 || r.Reasons[0].Code != "high_following_ratio" || r.Reasons[0].Penalty != 40 {
+		t.Errorf("Expected the reason to be returned with the score, got %v", r)
+	}
+	if r.RulesetVersion != "test" {
+		t.Errorf("Expected the ruleset version, got %q", r.RulesetVersion)
+	}
+}
+
+func TestEstimatePriceBatch(t *testing.T) {
+	client := newTestClient(t, NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, &mockMetrics{}))
+
+	resp, err := client.EstimatePriceBatch(context.Background(), &pb.PriceBatchRequest{
+		Requests: []*pb.PriceRequest{{Followers: 100}, {Followers: 300}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resp.Responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(resp.Responses))
+	}
+
+	for i, followers := range []float64{100, 300} {
+		r := resp.Responses[i]
+		if r.Currency != "USD" || r.RulesetVersion != "test" || len(r.Estimates) != 1 {
+			t.Fatalf("Response %d: unexpected %v", i, r)
+		}
+		e := r.Estimates[0]
+		if e.ContentType != "post" || e.Low != followers/2 || e.Expected != followers || e.High != followers*2 {
+			t.Errorf("Response %d: unexpected estimate %v", i, e)
+		}
+	}
+}
+
+func TestOversizedBatchesAreRejected(t *testing.T) {
+	metrics := &mockMetrics{}
+	client := newTestClient(t, NewServer(mockCalculator{}, mockScorer{}, mockPricer{}, metrics))
+	ctx := context.Background()
+
+	engagement := make([]*pb.EngagementRequest, MaxBatchSize+1)
+	audience := make([]*pb.AudienceQualityRequest, MaxBatchSize+1)
+	prices := make([]*pb.PriceRequest, MaxBatchSize+1)
+	for i := range engagement {
+		engagement[i], audience[i], prices[i] = &pb.EngagementRequest{}, &pb.AudienceQualityRequest{}, &pb.PriceRequest{}
+	}
+
+	_, engagementErr := client.CalculateEngagementBatch(ctx, &pb.EngagementBatchRequest{Requests: engagement})
+	_, audienceErr := client.ScoreAudienceQualityBatch(ctx, &pb.AudienceQualityBatchRequest{Requests: audience})
+	_, pricesErr := client.EstimatePriceBatch(ctx, &pb.PriceBatchRequest{Requests: prices})
+	for i, err := range []error{engagementErr, audienceErr, pricesErr} {
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Call %d: expected InvalidArgument, got %v", i, err)
+		}
+	}
+	if metrics.requests != 0 {
+		t.Errorf("Expected nothing to be scored, got %d requests", metrics.requests)
+	}
+
+	// The limit itself is accepted
+	resp, err := client.CalculateEngagementBatch(ctx, &pb.EngagementBatchRequest{Requests: engagement[:MaxBatchSize]})
+	if err != nil || len(resp.Responses) != MaxBatchSize {
+		t.Errorf("Expected a full batch to be scored, got %v", err)
+	}
+}
+
+func TestHealthReportsServing(t *testing.T) {
+	s := newMockServer()
+	health := healthpb.NewHealthClient(newTestConn(t, s))
+
+	for _, service := range []string{"", pb.AnalyticsService_ServiceDesc.ServiceName} {
+		resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Health check of %q failed: %v", service, err)
+		}
+		if resp.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q to be SERVING, got %v", service, resp.Status)
+		}
+	}
+}
+
+func TestReflectionIsOptIn(t *testing.T) {
+	listServices := func(s *Server) ([]string, error) {
+		stream, err := reflectionpb.NewServerReflectionClient(newTestConn(t, s)).ServerReflectionInfo(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+		}); err != nil {
+			return nil, err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, svc := range resp.GetListServicesResponse().GetService() {
+			names = append(names, svc.Name)
+		}
+		return names, nil
+	}
+
+	if _, err := listServices(newMockServer()); err == nil {
+		t.Error("Expected reflection to be disabled by default")
+	}
+
+	s := newMockServer()
+	s.EnableReflection()
+	names, err := listServices(s)
+	if err != nil {
+		t.Fatalf("Reflection failed: %v", err)
+	}
+	if !slices.Contains(names, pb.AnalyticsService_ServiceDesc.ServiceName) {
+		t.Errorf("Expected the analytics service to be listed, got %v", names)
+	}
+}
+
+func TestStopWaitsForOpenStreams(t *testing.T) {
+	s := newMockServer()
+	conn := newTestConn(t, s)
+	health := healthpb.NewHealthClient(conn)
+
+	stream, err := pb.NewAnalyticsServiceClient(conn).StreamEngagement(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Make sure the stream reached the server before stopping
+	if err := stream.Send(&pb.EngagementRequest{Followers: 1000}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop(context.Background())
+		close(stopped)
+	}()
+
+	// Probes see the server going away while the stream is still open
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := health.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err == nil && resp.Status == healthpb.HealthCheckResponse_NOT_SERVING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected NOT_SERVING during shutdown, got %v (%v)", resp, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while a stream was still open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return once the stream was closed")
+	}
+}
+
+func TestStopCutsOffAfterDeadline(t *testing.T) {
+	s := newMockServer()
+	stream, err := newTestClient(t, s).StreamEngagement(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := stream.Send(&pb.EngagementRequest{Followers: 1000}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Stop(ctx) // Returns although the client never closes its stream
+
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected the stream to be cut off")
+	}
+}
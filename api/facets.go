@@ -0,0 +1,147 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// followerTiers are the industry-standard influencer size buckets
+var followerTiers = []struct {
+	Key  string
+	From int
+	To   int // 0 means unbounded
+}{
+	{"nano", 0, 10000},
+	{"micro", 10000, 100000},
+	{"macro", 100000, 1000000},
+	{"mega", 1000000, 0},
+}
+
+// engagementInterval is the width (in percentage points) of the engagement_rate histogram buckets
+const engagementInterval = 1.0
+
+// facetsResponse is the trimmed down view of the Elasticsearch aggregations returned by /facets
+type facetsResponse struct {
+	Total          int           `json:"total"`
+	Platforms      []termBucket  `json:"platforms"`
+	Categories     []termBucket  `json:"categories"`
+	Followers      []rangeBucket `json:"followers"`
+	EngagementRate []histBucket  `json:"engagement_rate"`
+}
+
+type termBucket struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type rangeBucket struct {
+	Key   string   `json:"key"`
+	From  *float64 `json:"from,omitempty"`
+	To    *float64 `json:"to,omitempty"`
+	Count int      `json:"count"`
+}
+
+type histBucket struct {
+	Key   float64 `json:"key"`
+	Count int     `json:"count"`
+}
+
+// esFacetsResult mirrors the parts of the Elasticsearch response we read
+type esFacetsResult struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+	} `json:"hits"`
+	Aggregations struct {
+		Platforms struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int    `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"platforms"`
+		Categories struct {
+			Buckets []struct {
+				Key      string `json:"key"`
+				DocCount int    `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"categories"`
+		Followers struct {
+			Buckets []struct {
+				Key      string   `json:"key"`
+				From     *float64 `json:"from"`
+				To       *float64 `json:"to"`
+				DocCount int      `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"followers"`
+		EngagementRate struct {
+			Buckets []struct {
+				Key      float64 `json:"key"`
+				DocCount int     `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"engagement_rate"`
+	} `json:"aggregations"`
+}
+
+// buildFacetsBody builds an aggregation-only request scoped to the same query and filters as /search.
+// Unlike /search, an empty query is allowed so the sidebar can show counts for the whole index.
+func buildFacetsBody(c *gin.Context) (map[string]interface{}, error) {
+	query, _, err := buildBoolQuery(c)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]interface{}, 0, len(followerTiers))
+	for _, tier := range followerTiers {
+		r := map[string]interface{}{"key": tier.Key, "from": tier.From}
+		if tier.To > 0 {
+			r["to"] = tier.To
+		}
+		ranges = append(ranges, r)
+	}
+
+	return map[string]interface{}{
+		"size":  0,
+		"query": query,
+		"aggs": map[string]interface{}{
+			"platforms": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "platform", "size": 20},
+			},
+			"categories": map[string]interface{}{
+				"terms": map[string]interface{}{"field": "category", "size": 50},
+			},
+			"followers": map[string]interface{}{
+				"range": map[string]interface{}{"field": "followers", "ranges": ranges},
+			},
+			"engagement_rate": map[string]interface{}{
+				"histogram": map[string]interface{}{"field": "engagement_rate", "interval": engagementInterval},
+			},
+		},
+	}, nil
+}
+
+// toFacetsResponse flattens the Elasticsearch aggregations into the public response
+func toFacetsResponse(res esFacetsResult) facetsResponse {
+	out := facetsResponse{
+		Total:          res.Hits.Total.Value,
+		Platforms:      []termBucket{},
+		Categories:     []termBucket{},
+		Followers:      []rangeBucket{},
+		EngagementRate: []histBucket{},
+	}
+
+	aggs := res.Aggregations
+	for _, b := range aggs.Platforms.Buckets {
+		out.Platforms = append(out.Platforms, termBucket{Key: b.Key, Count: b.DocCount})
+	}
+	for _, b := range aggs.Categories.Buckets {
+		out.Categories = append(out.Categories, termBucket{Key: b.Key, Count: b.DocCount})
+	}
+	for _, b := range aggs.Followers.Buckets {
+		out.Followers = append(out.Followers, rangeBucket{Key: b.Key, From: b.From, To: b.To, Count: b.DocCount})
+	}
+	for _, b := range aggs.EngagementRate.Buckets {
+		out.EngagementRate = append(out.EngagementRate, histBucket{Key: b.Key, Count: b.DocCount})
+	}
+
+	return out
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsIndexName is the time-series index the indexer appends a snapshot to on every ingest
+const metricsIndexName = "influencer-metrics"
+
+// historyIntervals maps the interval parameter to an Elasticsearch calendar interval
+var historyIntervals = map[string]string{
+	"day":  "1d",
+	"week": "1w", // Weeks start on Monday
+}
+
+const historyDateFormat = "2006-01-02"
+
+// historyResponse is the rollup returned by /influencers/:id/history
+type historyResponse struct {
+	ID       string         `json:"id"`
+	Interval string         `json:"interval"`
+	Points   []historyPoint `json:"points"`
+}
+
+type historyPoint struct {
+	Date           string  `json:"date"`            // First day of the period
+	Followers      int     `json:"followers"`       // Last value seen in the period
+	FollowerChange int     `json:"follower_change"` // Compared to the previous point
+	EngagementRate float64 `json:"engagement_rate"` // Average over the period
+	Samples        int     `json:"samples"`
+}
+
+// esHistoryResult mirrors the parts of the Elasticsearch response we read
+type esHistoryResult struct {
+	Aggregations struct {
+		History struct {
+			Buckets []struct {
+				KeyAsString string `json:"key_as_string"`
+				DocCount    int    `json:"doc_count"`
+				Followers   struct {
+					Top []struct {
+						Metrics struct {
+							Followers float64 `json:"followers"`
+						} `json:"metrics"`
+					} `json:"top"`
+				} `json:"followers"`
+				EngagementRate struct {
+					Value *float64 `json:"value"`
+				} `json:"engagement_rate"`
+			} `json:"buckets"`
+		} `json:"history"`
+	} `json:"aggregations"`
+}
+
+// buildHistoryBody builds a date histogram over the influencer's snapshots.
+// from and to (YYYY-MM-DD, inclusive) optionally bound the period.
+func buildHistoryBody(c *gin.Context) (map[string]interface{}, string, error) {
+	interval := c.DefaultQuery("interval", "day")
+	calendarInterval, ok := historyIntervals[interval]
+	if !ok {
+		return nil, "", fmt.Errorf("invalid interval %q: expected day or week", interval)
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{"term": map[string]interface{}{"influencer_id": c.Param("id")}},
+	}
+
+	bounds := map[string]interface{}{}
+	dates := map[string]time.Time{}
+	for param, op := range map[string]string{"from": "gte", "to": "lte"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		date, err := time.Parse(historyDateFormat, raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid %s %q: expected YYYY-MM-DD", param, raw)
+		}
+		bounds[op], dates[param] = raw, date
+	}
+	// An inverted range can never match, it is almost certainly a mistake
+	if from, ok := dates["from"]; ok {
+		if to, ok := dates["to"]; ok && from.After(to) {
+			return nil, "", fmt.Errorf("invalid range: from %s is after to %s", c.Query("from"), c.Query("to"))
+		}
+	}
+	if len(bounds) > 0 {
+		bounds["format"] = "yyyy-MM-dd"
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"timestamp": bounds},
+		})
+	}
+
+	return map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{"filter": filters},
+		},
+		"aggs": map[string]interface{}{
+			"history": map[string]interface{}{
+				"date_histogram": map[string]interface{}{
+					"field":             "timestamp",
+					"calendar_interval": calendarInterval,
+					"format":            "yyyy-MM-dd",
+					"min_doc_count":     1,
+				},
+				"aggs": map[string]interface{}{
+					// Followers is a running count, so the period is summarised by its latest value
+					"followers": map[string]interface{}{
+						"top_metrics": map[string]interface{}{
+							"metrics": map[string]interface{}{"field": "followers"},
+							"sort":    map[string]interface{}{"timestamp": "desc"},
+						},
+					},
+					"engagement_rate": map[string]interface{}{
+						"avg": map[string]interface{}{"field": "engagement_rate"},
+					},
+				},
+			},
+		},
+	}, interval, nil
+}
+
+// toHistoryResponse flattens the histogram buckets and computes the growth between points
+func toHistoryResponse(id, interval string, res esHistoryResult) historyResponse {
+	out := historyResponse{ID: id, Interval: interval, Points: []historyPoint{}}
+
+	for i, b := range res.Aggregations.History.Buckets {
+		p := historyPoint{Date: b.KeyAsString, Samples: b.DocCount}
+		if len(b.Followers.Top) > 0 {
+			p.Followers = int(b.Followers.Top[0].Metrics.Followers)
+		}
+		if b.EngagementRate.Value != nil {
+			p.EngagementRate = *b.EngagementRate.Value
+		}
+		if i > 0 {
+			p.FollowerChange = p.Followers - out.Points[i-1].Followers
+		}
+		out.Points = append(out.Points, p)
+	}
+
+	return out
+}
@@ -11,29 +11,22 @@ import (
 	"github.com/hammo/influScope/pkg/models"
 )
 
+const indexName = "influencers"
+
 // setupRouter allows us to pass in the dependency (ES Client) for testing
 func setupRouter(es *elasticsearch.Client) *gin.Engine {
 	r := gin.Default()
 
 	// Define the Search Endpoint
 	r.GET("/search", func(c *gin.Context) {
-		query := c.Query("q")
-		if query == "" {
-			c.JSON(400, gin.H{"error": "Query parameter 'q' is required"})
+		queryJSON, page, err := buildSearchBody(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
 		// Build the Elastic Query
 		var buf bytes.Buffer
-		queryJSON := map[string]interface{}{
-			"query": map[string]interface{}{
-				"multi_match": map[string]interface{}{
-					"query":     query,
-					"fields":    []string{"bio", "category", "username"},
-					"fuzziness": "AUTO",
-				},
-			},
-		}
 		if err := json.NewEncoder(&buf).Encode(queryJSON); err != nil {
 			c.JSON(500, gin.H{"error": "Failed to build query"})
 			return
@@ -42,7 +35,7 @@ func setupRouter(es *elasticsearch.Client) *gin.Engine {
 		// Execute Search
 		res, err := es.Search(
 			es.Search.WithContext(context.Background()),
-			es.Search.WithIndex("influencers"),
+			es.Search.WithIndex(indexName),
 			es.Search.WithBody(&buf),
 			es.Search.WithTrackTotalHits(true),
 		)
@@ -66,12 +59,25 @@ func setupRouter(es *elasticsearch.Client) *gin.Engine {
 
 		// Transform Elastic response into clean JSON
 		var influencers []models.Influencer
+		var total int
+		var lastSort []interface{}
 
 		// Safe extraction to avoid panics if "hits" is missing
 		if hitsMap, ok := r["hits"].(map[string]interface{}); ok {
+			// With track_total_hits=true the total is exact: {"value": n, "relation": "eq"}
+			if totalMap, ok := hitsMap["total"].(map[string]interface{}); ok {
+				if value, ok := totalMap["value"].(float64); ok {
+					total = int(value)
+				}
+			}
+
 			if hitsList, ok := hitsMap["hits"].([]interface{}); ok {
 				for _, hit := range hitsList {
-					source := hit.(map[string]interface{})["_source"]
+					hitMap := hit.(map[string]interface{})
+					source := hitMap["_source"]
+					if sortValues, ok := hitMap["sort"].([]interface{}); ok {
+						lastSort = sortValues
+					}
 					
 					tmp, err := json.Marshal(source)
 					if err != nil {
@@ -96,10 +102,144 @@ func setupRouter(es *elasticsearch.Client) *gin.Engine {
 			influencers = []models.Influencer{}
 		}
 
-		c.JSON(200, gin.H{
+		response := gin.H{
 			"count": len(influencers),
+			"total": total,
+			"size":  page.Size,
 			"data":  influencers,
-		})
+		}
+		if page.SearchAfter == nil {
+			response["from"] = page.From
+		}
+
+		// A full page means there may be more: hand out a cursor to continue after the last hit
+		if len(influencers) == page.Size && lastSort != nil {
+			cursor, err := encodeCursor(lastSort)
+			if err != nil {
+				log.Printf("Error encoding cursor: %v", err)
+			} else {
+				response["next_cursor"] = cursor
+			}
+		}
+
+		c.JSON(200, response)
+	})
+
+	// Facet counts for the filter sidebar, scoped to the same q and filters as /search
+	r.GET("/facets", func(c *gin.Context) {
+		queryJSON, err := buildFacetsBody(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(queryJSON); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to build query"})
+			return
+		}
+
+		res, err := es.Search(
+			es.Search.WithContext(c.Request.Context()),
+			es.Search.WithIndex(indexName),
+			es.Search.WithBody(&buf),
+			es.Search.WithTrackTotalHits(true),
+		)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Elasticsearch failed"})
+			return
+		}
+		defer res.Body.Close()
+
+		if res.IsError() {
+			c.JSON(500, gin.H{"error": "Elasticsearch returned an error"})
+			return
+		}
+
+		var result esFacetsResult
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			c.JSON(500, gin.H{"error": "Error parsing response"})
+			return
+		}
+
+		c.JSON(200, toFacetsResponse(result))
+	})
+
+	// Fetch a single profile by its Influencer.ID (stored as the Elasticsearch _id)
+	r.GET("/influencers/:id", func(c *gin.Context) {
+		res, err := es.Get(
+			indexName,
+			c.Param("id"),
+			es.Get.WithContext(c.Request.Context()),
+		)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Elasticsearch failed"})
+			return
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode == 404 {
+			c.JSON(404, gin.H{"error": "Influencer not found"})
+			return
+		}
+		if res.IsError() {
+			c.JSON(500, gin.H{"error": "Elasticsearch returned an error"})
+			return
+		}
+
+		var doc struct {
+			Found  bool              `json:"found"`
+			Source models.Influencer `json:"_source"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&doc); err != nil {
+			c.JSON(500, gin.H{"error": "Error parsing response"})
+			return
+		}
+		if !doc.Found {
+			c.JSON(404, gin.H{"error": "Influencer not found"})
+			return
+		}
+
+		c.JSON(200, doc.Source)
+	})
+
+	// Follower and engagement trend of one influencer, rolled up per day or week
+	r.GET("/influencers/:id/history", func(c *gin.Context) {
+		queryJSON, interval, err := buildHistoryBody(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(queryJSON); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to build query"})
+			return
+		}
+
+		res, err := es.Search(
+			es.Search.WithContext(c.Request.Context()),
+			es.Search.WithIndex(metricsIndexName),
+			es.Search.WithBody(&buf),
+		)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "Elasticsearch failed"})
+			return
+		}
+		defer res.Body.Close()
+
+		if res.IsError() {
+			c.JSON(500, gin.H{"error": "Elasticsearch returned an error"})
+			return
+		}
+
+		var result esHistoryResult
+		if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+			c.JSON(500, gin.H{"error": "Error parsing response"})
+			return
+		}
+
+		c.JSON(200, toHistoryResponse(c.Param("id"), interval, result))
 	})
 
 	return r
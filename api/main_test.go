@@ -6,16 +6,20 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/elastic/go-elasticsearch/v7"
 	"github.com/gin-gonic/gin"
+	"github.com/hammo/influScope/pkg/models"
 )
 
 // --- MOCK TRANSPORT (Reused logic) ---
 type MockElasticsearchTransport struct {
 	ResponseStatusCode int
 	ResponseBody       string
+	LastRequestBody    []byte // Captured so tests can inspect the generated query
 }
 
 func (m *MockElasticsearchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -33,6 +37,10 @@ func (m *MockElasticsearchTransport) RoundTrip(req *http.Request) (*http.Respons
 		}, nil
 	}
 
+	if req.Body != nil {
+		m.LastRequestBody, _ = io.ReadAll(req.Body)
+	}
+
 	// 2. Return our custom mock response for search queries
 	return &http.Response{
 		StatusCode: m.ResponseStatusCode,
@@ -43,6 +51,12 @@ func (m *MockElasticsearchTransport) RoundTrip(req *http.Request) (*http.Respons
 
 // --- HELPER TO CREATE MOCK CLIENT ---
 func getMockClient(statusCode int, body string) *elasticsearch.Client {
+	client, _ := getMockClientWithTransport(statusCode, body)
+	return client
+}
+
+// getMockClientWithTransport also returns the transport so tests can inspect the sent query
+func getMockClientWithTransport(statusCode int, body string) (*elasticsearch.Client, *MockElasticsearchTransport) {
 	mockTrans := &MockElasticsearchTransport{
 		ResponseStatusCode: statusCode,
 		ResponseBody:       body,
@@ -50,7 +64,7 @@ func getMockClient(statusCode int, body string) *elasticsearch.Client {
 	client, _ := elasticsearch.NewClient(elasticsearch.Config{
 		Transport: mockTrans,
 	})
-	return client
+	return client, mockTrans
 }
 
 // --- TEST CASES ---
@@ -171,4 +185,580 @@ func TestSearchEndpoint_ElasticError(t *testing.T) {
 	if w.Code != 500 {
 		t.Errorf("Expected status 500 when ES fails, got %d", w.Code)
 	}
-}
\ No newline at end of file
+}
+
+func TestSearchEndpoint_FiltersWithoutQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?platform=TikTok&category=Fashion&min_followers=50000&max_followers=500000", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 when only filters are set, got %d", w.Code)
+	}
+
+	var sent struct {
+		Query struct {
+			Bool struct {
+				Must   []interface{}            `json:"must"`
+				Filter []map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	if len(sent.Query.Bool.Must) != 0 {
+		t.Errorf("Expected no full-text clause without 'q', got %v", sent.Query.Bool.Must)
+	}
+	if len(sent.Query.Bool.Filter) != 3 {
+		t.Fatalf("Expected 3 filter clauses (platform, category, followers), got %d", len(sent.Query.Bool.Filter))
+	}
+
+	followers := sent.Query.Bool.Filter[2]["range"].(map[string]interface{})["followers"].(map[string]interface{})
+	if followers["gte"].(float64) != 50000 || followers["lte"].(float64) != 500000 {
+		t.Errorf("Unexpected followers range: %v", followers)
+	}
+}
+
+func TestSearchEndpoint_AudienceQualityFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?min_audience_quality=70&sort=-audience_quality", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Sort []map[string]interface{} `json:"sort"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	if len(sent.Query.Bool.Filter) != 1 || sent.Query.Bool.Filter[0]["range"]["audience_quality"]["gte"] != float64(70) {
+		t.Errorf("Expected an audience_quality >= 70 range, got %v", sent.Query.Bool.Filter)
+	}
+	if len(sent.Sort) == 0 || sent.Sort[0]["audience_quality"] != "desc" {
+		t.Errorf("Expected to sort by audience_quality first, got %v", sent.Sort)
+	}
+}
+
+func TestSearchEndpoint_BudgetFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?max_price=500&price_type=reel&sort=price", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Sort []map[string]interface{} `json:"sort"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	if len(sent.Query.Bool.Filter) != 1 || sent.Query.Bool.Filter[0]["range"]["prices.reel.expected"]["lte"] != float64(500) {
+		t.Errorf("Expected a reel price <= 500 range, got %v", sent.Query.Bool.Filter)
+	}
+	if len(sent.Sort) == 0 || sent.Sort[0]["prices.reel.expected"] != "asc" {
+		t.Errorf("Expected to sort by the reel price first, got %v", sent.Sort)
+	}
+}
+
+func TestSearchEndpoint_ExcludeDegraded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?exclude_degraded=true", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]map[string]map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	if len(sent.Query.Bool.Filter) != 1 || sent.Query.Bool.Filter[0]["bool"]["must_not"]["term"]["degraded"] != true {
+		t.Errorf("Expected degraded profiles to be excluded, got %v", sent.Query.Bool.Filter)
+	}
+}
+
+func TestSearchEndpoint_EnrichmentFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?enrichment_status=failed,partial&stale_after=36h&sort=enriched_at", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent struct {
+		Query struct {
+			Bool struct {
+				Filter []map[string]interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Sort []map[string]interface{} `json:"sort"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	if len(sent.Query.Bool.Filter) != 2 {
+		t.Fatalf("Expected 2 filter clauses (status, staleness), got %v", sent.Query.Bool.Filter)
+	}
+	statuses := sent.Query.Bool.Filter[0]["terms"].(map[string]interface{})["enrichment_status"].([]interface{})
+	if len(statuses) != 2 || statuses[0] != "failed" || statuses[1] != "partial" {
+		t.Errorf("Expected failed and partial statuses, got %v", statuses)
+	}
+	// Never enriched profiles are stale too
+	stale, _ := json.Marshal(sent.Query.Bool.Filter[1])
+	if !strings.Contains(string(stale), `"lt":"now-129600s"`) || !strings.Contains(string(stale), `"exists":{"field":"enriched_at"}`) {
+		t.Errorf("Expected enriched_at older than 36h or missing, got %s", stale)
+	}
+	if len(sent.Sort) == 0 || sent.Sort[0]["enriched_at"] != "asc" {
+		t.Errorf("Expected to sort by enriched_at first, got %v", sent.Sort)
+	}
+}
+
+func TestSearchEndpoint_InvalidFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(200, "{}")
+	router := setupRouter(client)
+
+	tests := []string{
+		"/search?q=tech&min_followers=lots",
+		"/search?max_engagement=-1",
+		"/search?min_audience_quality=high",
+		"/search?max_price=500&price_type=tweet",
+		"/search?max_price=cheap",
+		"/search?exclude_degraded=maybe",
+		"/search?enrichment_status=pending",
+		"/search?stale_after=yesterday",
+		"/search?min_followers=500000&max_followers=1000",
+		"/search?min_engagement=5.5&max_engagement=2",
+	}
+
+	for _, url := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestSearchEndpoint_Pagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Two hits on a page of size 2 out of 57 matches: the API should report the real total and a cursor
+	mockESResponse := `{
+        "hits": {
+            "total": {"value": 57, "relation": "eq"},
+            "hits": [
+                {"_source": {"id": "a", "username": "first"}, "sort": [1.5, "a"]},
+                {"_source": {"id": "b", "username": "second"}, "sort": [1.2, "b"]}
+            ]
+        }
+    }`
+
+	client, transport := getMockClientWithTransport(200, mockESResponse)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?q=tech&page=3&size=2", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent map[string]interface{}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+	if sent["from"].(float64) != 4 || sent["size"].(float64) != 2 {
+		t.Errorf("Expected from=4 size=2, got from=%v size=%v", sent["from"], sent["size"])
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response["total"].(float64) != 57 {
+		t.Errorf("Expected total 57, got %v", response["total"])
+	}
+	if response["count"].(float64) != 2 {
+		t.Errorf("Expected count 2, got %v", response["count"])
+	}
+
+	// Follow the cursor: the next request must use search_after with the last hit's sort values
+	cursor, ok := response["next_cursor"].(string)
+	if !ok || cursor == "" {
+		t.Fatalf("Expected a next_cursor on a full page, got %v", response["next_cursor"])
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/search?q=tech&size=2&cursor="+cursor, nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 with cursor, got %d", w.Code)
+	}
+
+	sent = map[string]interface{}{}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+	after, ok := sent["search_after"].([]interface{})
+	if !ok || len(after) != 2 || after[0].(float64) != 1.2 || after[1] != "b" {
+		t.Errorf("Expected search_after [1.2 b], got %v", sent["search_after"])
+	}
+	if _, ok := sent["from"]; ok {
+		t.Errorf("Expected no 'from' when paginating with a cursor")
+	}
+}
+
+func TestSearchEndpoint_InvalidPagination(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(200, "{}")
+	router := setupRouter(client)
+
+	tests := []string{
+		"/search?q=tech&size=1000",
+		"/search?q=tech&page=0",
+		"/search?q=tech&from=9990&size=20",
+		"/search?q=tech&page=9223372036854775807",
+		"/search?q=tech&from=9223372036854775807",
+		"/search?q=tech&page=2&cursor=abc",
+		"/search?q=tech&cursor=not-a-cursor",
+	}
+
+	for _, url := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestSearchEndpoint_Sort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client, transport := getMockClientWithTransport(200, `{"hits": {"hits": []}}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/search?q=tech&sort=-followers,engagement_rate", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var sent struct {
+		Sort []map[string]string `json:"sort"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+
+	expected := []map[string]string{
+		{"followers": "desc"},
+		{"engagement_rate": "asc"},
+		{"id": "asc"},
+	}
+	if !reflect.DeepEqual(sent.Sort, expected) {
+		t.Errorf("Expected sort %v, got %v", expected, sent.Sort)
+	}
+}
+
+func TestSearchEndpoint_InvalidSort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(200, "{}")
+	router := setupRouter(client)
+
+	tests := []string{
+		"/search?q=tech&sort=bio",
+		"/search?q=tech&sort=followers,-followers",
+		"/search?q=tech&sort=-relevance",
+	}
+
+	for _, url := range tests {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("Expected status 400 for %s, got %d", url, w.Code)
+		}
+	}
+}
+
+func TestSortableFieldsMatchModel(t *testing.T) {
+	// Every public sort key must be a real field of the indexed document
+	fields := map[string]bool{}
+	typ := reflect.TypeOf(models.Influencer{})
+	for i := 0; i < typ.NumField(); i++ {
+		name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+		fields[name] = true
+	}
+
+	for key := range sortableFields {
+		if !fields[key] {
+			t.Errorf("Sort key '%s' is not a models.Influencer field", key)
+		}
+	}
+}
+
+func TestGetInfluencer_Success(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockESResponse := `{
+        "_index": "influencers",
+        "_id": "abc-123",
+        "found": true,
+        "_source": {"id": "abc-123", "username": "test_guru", "platform": "TikTok", "followers": 5000}
+    }`
+
+	client := getMockClient(200, mockESResponse)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/influencers/abc-123", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var inf models.Influencer
+	if err := json.Unmarshal(w.Body.Bytes(), &inf); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if inf.ID != "abc-123" || inf.Username != "test_guru" {
+		t.Errorf("Unexpected influencer returned: %+v", inf)
+	}
+}
+
+func TestGetInfluencer_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(404, `{"_index": "influencers", "_id": "missing", "found": false}`)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/influencers/missing", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 404 {
+		t.Errorf("Expected status 404 for unknown id, got %d", w.Code)
+	}
+}
+
+func TestFacetsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockESResponse := `{
+        "hits": {"total": {"value": 42, "relation": "eq"}, "hits": []},
+        "aggregations": {
+            "platforms": {"buckets": [{"key": "TikTok", "doc_count": 30}, {"key": "Instagram", "doc_count": 12}]},
+            "categories": {"buckets": [{"key": "Fashion", "doc_count": 42}]},
+            "followers": {"buckets": [
+                {"key": "nano", "from": 0, "to": 10000, "doc_count": 5},
+                {"key": "mega", "from": 1000000, "doc_count": 2}
+            ]},
+            "engagement_rate": {"buckets": [{"key": 3.0, "doc_count": 20}, {"key": 4.0, "doc_count": 22}]}
+        }
+    }`
+
+	client, transport := getMockClientWithTransport(200, mockESResponse)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/facets?q=style&category=Fashion", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	// The aggregations must be scoped to the same query and filters as /search
+	var sent struct {
+		Size  int `json:"size"`
+		Query struct {
+			Bool struct {
+				Must   []interface{} `json:"must"`
+				Filter []interface{} `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		Aggs map[string]interface{} `json:"aggs"`
+	}
+	if err := json.Unmarshal(transport.LastRequestBody, &sent); err != nil {
+		t.Fatalf("Failed to decode query sent to ES: %v", err)
+	}
+	if sent.Size != 0 || len(sent.Query.Bool.Must) != 1 || len(sent.Query.Bool.Filter) != 1 {
+		t.Errorf("Expected a size 0 request scoped to q and filters, got %s", transport.LastRequestBody)
+	}
+	for _, agg := range []string{"platforms", "categories", "followers", "engagement_rate"} {
+		if _, ok := sent.Aggs[agg]; !ok {
+			t.Errorf("Expected aggregation '%s' in request", agg)
+		}
+	}
+
+	var response facetsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Total != 42 {
+		t.Errorf("Expected total 42, got %d", response.Total)
+	}
+	if len(response.Platforms) != 2 || response.Platforms[0].Key != "TikTok" || response.Platforms[0].Count != 30 {
+		t.Errorf("Unexpected platform buckets: %+v", response.Platforms)
+	}
+	if len(response.Followers) != 2 || response.Followers[1].Key != "mega" || response.Followers[1].To != nil {
+		t.Errorf("Unexpected follower buckets: %+v", response.Followers)
+	}
+	if len(response.EngagementRate) != 2 || response.EngagementRate[1].Count != 22 {
+		t.Errorf("Unexpected engagement buckets: %+v", response.EngagementRate)
+	}
+}
+
+func TestFacetsEndpoint_NoCriteria(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(200, `{"hits": {"total": {"value": 0}, "hits": []}}`)
+	router := setupRouter(client)
+
+	// Unlike /search, facets over the whole index are allowed
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/facets", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Errorf("Expected status 200 without criteria, got %d", w.Code)
+	}
+}
+
+func TestInfluencerHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockESResponse := `{
+        "hits": {"total": {"value": 5, "relation": "eq"}, "hits": []},
+        "aggregations": {
+            "history": {"buckets": [
+                {"key_as_string": "2026-09-28", "doc_count": 3,
+                 "followers": {"top": [{"sort": ["2026-10-02T10:00:00Z"], "metrics": {"followers": 10000}}]},
+                 "engagement_rate": {"value": 4.5}},
+                {"key_as_string": "2026-10-05", "doc_count": 2,
+                 "followers": {"top": [{"sort": ["2026-10-09T10:00:00Z"], "metrics": {"followers": 12500}}]},
+                 "engagement_rate": {"value": 5.0}}
+            ]}
+        }
+    }`
+
+	client, transport := getMockClientWithTransport(200, mockESResponse)
+	router := setupRouter(client)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/influencers/abc-123/history?interval=week&from=2026-09-01", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The rollup is computed by Elasticsearch over the influencer's snapshots
+	sent := string(transport.LastRequestBody)
+	for _, want := range []string{`"influencer_id":"abc-123"`, `"calendar_interval":"1w"`, `"gte":"2026-09-01"`} {
+		if !strings.Contains(sent, want) {
+			t.Errorf("Expected query to contain %s, got %s", want, sent)
+		}
+	}
+
+	var resp historyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.ID != "abc-123" || resp.Interval != "week" || len(resp.Points) != 2 {
+		t.Fatalf("Unexpected history: %+v", resp)
+	}
+	second := resp.Points[1]
+	if second.Date != "2026-10-05" || second.Followers != 12500 || second.FollowerChange != 2500 || second.EngagementRate != 5.0 || second.Samples != 2 {
+		t.Errorf("Unexpected second point: %+v", second)
+	}
+	if resp.Points[0].FollowerChange != 0 {
+		t.Errorf("Expected no change on the first point, got %d", resp.Points[0].FollowerChange)
+	}
+}
+
+func TestInfluencerHistory_InvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	client := getMockClient(200, "{}")
+	router := setupRouter(client)
+
+	for _, query := range []string{"interval=month", "from=yesterday", "from=2026-03-01&to=2026-02-01"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/influencers/abc-123/history?"+query, nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != 400 {
+			t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+		}
+	}
+}
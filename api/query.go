@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hammo/influScope/pkg/models"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+	// Elasticsearch refuses from+size past index.max_result_window, deeper pages need the cursor
+	maxResultWindow = 10000
+)
+
+// sortableFields maps the public sort keys (models.Influencer JSON names) to the
+// Elasticsearch field to sort on (see the indexer mapping). Username is text and sorts on its keyword sub-field.
+var sortableFields = map[string]string{
+	"followers":        "followers",
+	"engagement_rate":  "engagement_rate",
+	"audience_quality": "audience_quality",
+	"enriched_at":      "enriched_at",
+	"username":         "username.keyword",
+	"platform":         "platform",
+	"category":         "category",
+}
+
+// relevanceSort is the pseudo sort key for the text match score (always best match first)
+const relevanceSort = "relevance"
+
+// priceSort sorts on the expected price of the content type picked by price_type
+const priceSort = "price"
+
+// priceTypes are the content types analytics prices, price_type defaults to the first one
+var priceTypes = []string{"post", "story", "reel", "video"}
+
+// enrichmentStatuses are the values enrichment_status can be filtered on
+var enrichmentStatuses = []string{models.EnrichmentComplete, models.EnrichmentPartial, models.EnrichmentFailed}
+
+// tieBreaker is appended to every sort so equal values come back in a stable order
+var tieBreaker = map[string]interface{}{"id": "asc"}
+
+// pagination describes which slice of the hits a request wants.
+// Either From is used (page/size or from/size) or SearchAfter (cursor), never both.
+type pagination struct {
+	From        int
+	Size        int
+	SearchAfter []interface{}
+}
+
+// buildSearchBody assembles the full Elasticsearch request body for /search
+func buildSearchBody(c *gin.Context) (map[string]interface{}, pagination, error) {
+	query, err := buildSearchQuery(c)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+
+	sort, err := parseSort(c)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+
+	page, err := parsePagination(c)
+	if err != nil {
+		return nil, pagination{}, err
+	}
+	// A cursor only makes sense for the sort that produced it
+	if page.SearchAfter != nil && len(page.SearchAfter) != len(sort) {
+		return nil, pagination{}, fmt.Errorf("query parameter 'cursor' does not match the requested sort")
+	}
+
+	body := map[string]interface{}{
+		"query": query,
+		"size":  page.Size,
+		"sort":  sort,
+	}
+	if page.SearchAfter != nil {
+		body["search_after"] = page.SearchAfter
+	} else {
+		body["from"] = page.From
+	}
+
+	return body, page, nil
+}
+
+// parseSort turns "sort=-followers,engagement_rate" into an Elasticsearch sort.
+// A leading '-' sorts descending. Without a sort parameter results are ordered by relevance.
+// The id tie-breaker is always last, which is also what makes search_after cursors stable.
+func parseSort(c *gin.Context) ([]interface{}, error) {
+	keys := splitValues(c.Query("sort"))
+	if len(keys) == 0 {
+		keys = []string{relevanceSort}
+	}
+
+	sort := make([]interface{}, 0, len(keys)+1)
+	seen := map[string]bool{}
+
+	for _, key := range keys {
+		order := "asc"
+		if strings.HasPrefix(key, "-") {
+			order = "desc"
+			key = key[1:]
+		}
+
+		if seen[key] {
+			return nil, fmt.Errorf("sort field '%s' is listed more than once", key)
+		}
+		seen[key] = true
+
+		if key == relevanceSort {
+			if order == "desc" {
+				return nil, fmt.Errorf("sort key '%s' cannot be reversed, it is always best match first", relevanceSort)
+			}
+			sort = append(sort, map[string]interface{}{"_score": "desc"})
+			continue
+		}
+		if key == priceSort {
+			field, err := priceField(c)
+			if err != nil {
+				return nil, err
+			}
+			sort = append(sort, map[string]interface{}{field: order})
+			continue
+		}
+
+		field, ok := sortableFields[key]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort on '%s', allowed fields: %s", key, strings.Join(sortKeys(), ", "))
+		}
+		sort = append(sort, map[string]interface{}{field: order})
+	}
+
+	return append(sort, tieBreaker), nil
+}
+
+// sortKeys lists the accepted sort keys, sorted for stable error messages
+func sortKeys() []string {
+	keys := []string{relevanceSort, priceSort}
+	for k := range sortableFields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// parsePagination reads page/size, from/size or cursor/size from the query string
+func parsePagination(c *gin.Context) (pagination, error) {
+	p := pagination{Size: defaultPageSize}
+
+	if raw := c.Query("size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 || size > maxPageSize {
+			return p, fmt.Errorf("query parameter 'size' must be between 1 and %d", maxPageSize)
+		}
+		p.Size = size
+	}
+
+	rawPage, rawFrom, rawCursor := c.Query("page"), c.Query("from"), c.Query("cursor")
+	set := 0
+	for _, raw := range []string{rawPage, rawFrom, rawCursor} {
+		if raw != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return p, fmt.Errorf("query parameters 'page', 'from' and 'cursor' are mutually exclusive")
+	}
+
+	switch {
+	case rawCursor != "":
+		after, err := decodeCursor(rawCursor)
+		if err != nil {
+			return p, err
+		}
+		p.SearchAfter = after
+		return p, nil
+	case rawPage != "":
+		page, err := strconv.Atoi(rawPage)
+		if err != nil || page < 1 {
+			return p, fmt.Errorf("query parameter 'page' must be a positive integer")
+		}
+		// Checked before multiplying, a huge page would overflow into a valid looking offset
+		if page > maxResultWindow/p.Size+1 {
+			return p, fmt.Errorf("cannot page past %d results, use 'cursor' for deep pagination", maxResultWindow)
+		}
+		p.From = (page - 1) * p.Size
+	case rawFrom != "":
+		from, err := strconv.Atoi(rawFrom)
+		if err != nil || from < 0 {
+			return p, fmt.Errorf("query parameter 'from' must be a non-negative integer")
+		}
+		if from > maxResultWindow {
+			return p, fmt.Errorf("cannot page past %d results, use 'cursor' for deep pagination", maxResultWindow)
+		}
+		p.From = from
+	}
+
+	if p.From+p.Size > maxResultWindow {
+		return p, fmt.Errorf("cannot page past %d results, use 'cursor' for deep pagination", maxResultWindow)
+	}
+	return p, nil
+}
+
+// encodeCursor turns the sort values of the last hit into an opaque token
+func encodeCursor(sortValues []interface{}) (string, error) {
+	raw, err := json.Marshal(sortValues)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(cursor string) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("query parameter 'cursor' is invalid")
+	}
+
+	// UseNumber keeps long sort values exact when they are sent back to Elasticsearch
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var values []interface{}
+	if err := dec.Decode(&values); err != nil || len(values) == 0 {
+		return nil, fmt.Errorf("query parameter 'cursor' is invalid")
+	}
+	return values, nil
+}
+
+// buildSearchQuery translates the /search query string into an Elasticsearch bool query.
+// The free-text "q" stays a fuzzy multi_match, every structured filter becomes a
+// non-scoring filter clause. "q" is only required when no filter is given.
+func buildSearchQuery(c *gin.Context) (map[string]interface{}, error) {
+	query, hasCriteria, err := buildBoolQuery(c)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCriteria {
+		return nil, fmt.Errorf("query parameter 'q' is required when no filter is set")
+	}
+	return query, nil
+}
+
+// buildBoolQuery builds the bool query shared by /search and /facets.
+// hasCriteria is false when neither "q" nor any filter was given (the query then matches everything).
+func buildBoolQuery(c *gin.Context) (map[string]interface{}, bool, error) {
+	filters, err := buildFilters(c)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+
+	boolQuery := map[string]interface{}{
+		"filter": filters,
+	}
+	if query != "" {
+		boolQuery["must"] = []interface{}{
+			map[string]interface{}{
+				"multi_match": map[string]interface{}{
+					"query":     query,
+					"fields":    []string{"bio", "category.text", "username"},
+					"fuzziness": "AUTO",
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{"bool": boolQuery}, query != "" || len(filters) > 0, nil
+}
+
+// buildFilters collects the exact-match and range filters from the query string
+func buildFilters(c *gin.Context) ([]interface{}, error) {
+	filters := []interface{}{}
+
+	// Exact matches on keyword fields (comma separated values are OR'ed together)
+	for _, field := range []string{"platform", "category"} {
+		if values := splitValues(c.Query(field)); len(values) > 0 {
+			filters = append(filters, map[string]interface{}{
+				"terms": map[string]interface{}{field: values},
+			})
+		}
+	}
+
+	// Numeric ranges
+	followers, err := rangeFilter(c, "followers", "min_followers", "max_followers", parseInt)
+	if err != nil {
+		return nil, err
+	}
+	if followers != nil {
+		filters = append(filters, followers)
+	}
+
+	engagement, err := rangeFilter(c, "engagement_rate", "min_engagement", "max_engagement", parseFloat)
+	if err != nil {
+		return nil, err
+	}
+	if engagement != nil {
+		filters = append(filters, engagement)
+	}
+
+	// Profiles analytics has not scored yet have no audience_quality and never match
+	quality, err := rangeFilter(c, "audience_quality", "min_audience_quality", "max_audience_quality", parseInt)
+	if err != nil {
+		return nil, err
+	}
+	if quality != nil {
+		filters = append(filters, quality)
+	}
+
+	// Budget, on the expected price of one content type
+	field, err := priceField(c)
+	if err != nil {
+		return nil, err
+	}
+	price, err := rangeFilter(c, field, "min_price", "max_price", parseFloat)
+	if err != nil {
+		return nil, err
+	}
+	if price != nil {
+		filters = append(filters, price)
+	}
+
+	// Profiles indexed with fallback values while analytics was down. Documents from before
+	// the flag existed have no degraded field and are kept.
+	if raw := c.Query("exclude_degraded"); raw != "" {
+		exclude, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("query parameter 'exclude_degraded' must be true or false")
+		}
+		if exclude {
+			filters = append(filters, map[string]interface{}{
+				"bool": map[string]interface{}{
+					"must_not": map[string]interface{}{"term": map[string]interface{}{"degraded": true}},
+				},
+			})
+		}
+	}
+
+	enrichment, err := enrichmentFilters(c)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, enrichment...)
+
+	return filters, nil
+}
+
+// enrichmentFilters select profiles by how analytics enrichment went, so ops can find the
+// ones to re-enrich: enrichment_status=failed,partial and/or stale_after=24h
+func enrichmentFilters(c *gin.Context) ([]interface{}, error) {
+	filters := []interface{}{}
+
+	if values := splitValues(c.Query("enrichment_status")); len(values) > 0 {
+		for _, v := range values {
+			if !slices.Contains(enrichmentStatuses, v) {
+				return nil, fmt.Errorf("query parameter 'enrichment_status' must be among %s", strings.Join(enrichmentStatuses, ", "))
+			}
+		}
+		filters = append(filters, map[string]interface{}{
+			"terms": map[string]interface{}{"enrichment_status": values},
+		})
+	}
+
+	// Last scored longer ago than the duration, or never scored at all
+	if raw := c.Query("stale_after"); raw != "" {
+		age, err := time.ParseDuration(raw)
+		if err != nil || age < time.Second {
+			return nil, fmt.Errorf("query parameter 'stale_after' must be a duration of at least 1s, such as 24h")
+		}
+		filters = append(filters, map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{
+						"range": map[string]interface{}{
+							"enriched_at": map[string]interface{}{"lt": fmt.Sprintf("now-%ds", int64(age.Seconds()))},
+						},
+					},
+					map[string]interface{}{
+						"bool": map[string]interface{}{
+							"must_not": map[string]interface{}{"exists": map[string]interface{}{"field": "enriched_at"}},
+						},
+					},
+				},
+				"minimum_should_match": 1,
+			},
+		})
+	}
+
+	return filters, nil
+}
+
+// priceField is the expected price field of the requested price_type (post by default)
+func priceField(c *gin.Context) (string, error) {
+	contentType := c.DefaultQuery("price_type", priceTypes[0])
+	if !slices.Contains(priceTypes, contentType) {
+		return "", fmt.Errorf("query parameter 'price_type' must be one of %s", strings.Join(priceTypes, ", "))
+	}
+	return "prices." + contentType + ".expected", nil
+}
+
+// rangeFilter builds a range clause on field from the optional min/max params
+func rangeFilter(c *gin.Context, field, minParam, maxParam string, parse func(string, string) (interface{}, error)) (map[string]interface{}, error) {
+	bounds := map[string]interface{}{}
+
+	if raw := c.Query(minParam); raw != "" {
+		v, err := parse(minParam, raw)
+		if err != nil {
+			return nil, err
+		}
+		bounds["gte"] = v
+	}
+	if raw := c.Query(maxParam); raw != "" {
+		v, err := parse(maxParam, raw)
+		if err != nil {
+			return nil, err
+		}
+		bounds["lte"] = v
+	}
+
+	if len(bounds) == 0 {
+		return nil, nil
+	}
+	// An inverted range can never match, it is almost certainly a mistake
+	if min, ok := bounds["gte"]; ok {
+		if max, ok := bounds["lte"]; ok && greater(min, max) {
+			return nil, fmt.Errorf("query parameter '%s' must not be greater than '%s'", minParam, maxParam)
+		}
+	}
+	return map[string]interface{}{
+		"range": map[string]interface{}{field: bounds},
+	}, nil
+}
+
+// greater compares two bounds returned by the same parse function
+func greater(a, b interface{}) bool {
+	switch a := a.(type) {
+	case int:
+		return a > b.(int)
+	case float64:
+		return a > b.(float64)
+	}
+	return false
+}
+
+func parseInt(param, raw string) (interface{}, error) {
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("query parameter '%s' must be a non-negative integer", param)
+	}
+	return v, nil
+}
+
+func parseFloat(param, raw string) (interface{}, error) {
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v < 0 {
+		return nil, fmt.Errorf("query parameter '%s' must be a non-negative number", param)
+	}
+	return v, nil
+}
+
+// splitValues turns "TikTok, Instagram" into ["TikTok", "Instagram"]
+func splitValues(raw string) []string {
+	var values []string
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -24,6 +24,18 @@ const _ = grpc.SupportPackageIsVersion7
 type AnalyticsServiceClient interface {
 	// Takes raw stats and returns a calculated engagement rate
 	CalculateEngagement(ctx context.Context, in *EngagementRequest, opts ...grpc.CallOption) (*EngagementResponse, error)
+	// Scores several profiles in one round trip, responses are in request order, at most 500 requests per batch
+	CalculateEngagementBatch(ctx context.Context, in *EngagementBatchRequest, opts ...grpc.CallOption) (*EngagementBatchResponse, error)
+	// Long-lived variant for continuous producers, one response per request, in order
+	StreamEngagement(ctx context.Context, opts ...grpc.CallOption) (AnalyticsService_StreamEngagementClient, error)
+	// Estimates how genuine an account's audience is, 0 (fake) to 100 (authentic)
+	ScoreAudienceQuality(ctx context.Context, in *AudienceQualityRequest, opts ...grpc.CallOption) (*AudienceQualityResponse, error)
+	// Batch variant of ScoreAudienceQuality, responses are in request order, at most 500 requests per batch
+	ScoreAudienceQualityBatch(ctx context.Context, in *AudienceQualityBatchRequest, opts ...grpc.CallOption) (*AudienceQualityBatchResponse, error)
+	// Estimates the fee of a sponsored post for each content type the platform offers
+	EstimatePrice(ctx context.Context, in *PriceRequest, opts ...grpc.CallOption) (*PriceResponse, error)
+	// Batch variant of EstimatePrice, responses are in request order, at most 500 requests per batch
+	EstimatePriceBatch(ctx context.Context, in *PriceBatchRequest, opts ...grpc.CallOption) (*PriceBatchResponse, error)
 }
 
 type analyticsServiceClient struct {
@@ -43,12 +55,100 @@ func (c *analyticsServiceClient) CalculateEngagement(ctx context.Context, in *En
 	return out, nil
 }
 
+func (c *analyticsServiceClient) CalculateEngagementBatch(ctx context.Context, in *EngagementBatchRequest, opts ...grpc.CallOption) (*EngagementBatchResponse, error) {
+	out := new(EngagementBatchResponse)
+	err := c.cc.Invoke(ctx, "/analytics.AnalyticsService/CalculateEngagementBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) StreamEngagement(ctx context.Context, opts ...grpc.CallOption) (AnalyticsService_StreamEngagementClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AnalyticsService_ServiceDesc.Streams[0], "/analytics.AnalyticsService/StreamEngagement", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &analyticsServiceStreamEngagementClient{stream}
+	return x, nil
+}
+
+type AnalyticsService_StreamEngagementClient interface {
+	Send(*EngagementRequest) error
+	Recv() (*EngagementResponse, error)
+	grpc.ClientStream
+}
+
+type analyticsServiceStreamEngagementClient struct {
+	grpc.ClientStream
+}
+
+func (x *analyticsServiceStreamEngagementClient) Send(m *EngagementRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *analyticsServiceStreamEngagementClient) Recv() (*EngagementResponse, error) {
+	m := new(EngagementResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *analyticsServiceClient) ScoreAudienceQuality(ctx context.Context, in *AudienceQualityRequest, opts ...grpc.CallOption) (*AudienceQualityResponse, error) {
+	out := new(AudienceQualityResponse)
+	err := c.cc.Invoke(ctx, "/analytics.AnalyticsService/ScoreAudienceQuality", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) ScoreAudienceQualityBatch(ctx context.Context, in *AudienceQualityBatchRequest, opts ...grpc.CallOption) (*AudienceQualityBatchResponse, error) {
+	out := new(AudienceQualityBatchResponse)
+	err := c.cc.Invoke(ctx, "/analytics.AnalyticsService/ScoreAudienceQualityBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) EstimatePrice(ctx context.Context, in *PriceRequest, opts ...grpc.CallOption) (*PriceResponse, error) {
+	out := new(PriceResponse)
+	err := c.cc.Invoke(ctx, "/analytics.AnalyticsService/EstimatePrice", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analyticsServiceClient) EstimatePriceBatch(ctx context.Context, in *PriceBatchRequest, opts ...grpc.CallOption) (*PriceBatchResponse, error) {
+	out := new(PriceBatchResponse)
+	err := c.cc.Invoke(ctx, "/analytics.AnalyticsService/EstimatePriceBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AnalyticsServiceServer is the server API for AnalyticsService service.
 // All implementations should embed UnimplementedAnalyticsServiceServer
 // for forward compatibility
 type AnalyticsServiceServer interface {
 	// Takes raw stats and returns a calculated engagement rate
 	CalculateEngagement(context.Context, *EngagementRequest) (*EngagementResponse, error)
+	// Scores several profiles in one round trip, responses are in request order, at most 500 requests per batch
+	CalculateEngagementBatch(context.Context, *EngagementBatchRequest) (*EngagementBatchResponse, error)
+	// Long-lived variant for continuous producers, one response per request, in order
+	StreamEngagement(AnalyticsService_StreamEngagementServer) error
+	// Estimates how genuine an account's audience is, 0 (fake) to 100 (authentic)
+	ScoreAudienceQuality(context.Context, *AudienceQualityRequest) (*AudienceQualityResponse, error)
+	// Batch variant of ScoreAudienceQuality, responses are in request order, at most 500 requests per batch
+	ScoreAudienceQualityBatch(context.Context, *AudienceQualityBatchRequest) (*AudienceQualityBatchResponse, error)
+	// Estimates the fee of a sponsored post for each content type the platform offers
+	EstimatePrice(context.Context, *PriceRequest) (*PriceResponse, error)
+	// Batch variant of EstimatePrice, responses are in request order, at most 500 requests per batch
+	EstimatePriceBatch(context.Context, *PriceBatchRequest) (*PriceBatchResponse, error)
 }
 
 // UnimplementedAnalyticsServiceServer should be embedded to have forward compatible implementations.
@@ -58,6 +158,24 @@ type UnimplementedAnalyticsServiceServer struct {
 func (UnimplementedAnalyticsServiceServer) CalculateEngagement(context.Context, *EngagementRequest) (*EngagementResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CalculateEngagement not implemented")
 }
+func (UnimplementedAnalyticsServiceServer) CalculateEngagementBatch(context.Context, *EngagementBatchRequest) (*EngagementBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateEngagementBatch not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) StreamEngagement(AnalyticsService_StreamEngagementServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEngagement not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) ScoreAudienceQuality(context.Context, *AudienceQualityRequest) (*AudienceQualityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScoreAudienceQuality not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) ScoreAudienceQualityBatch(context.Context, *AudienceQualityBatchRequest) (*AudienceQualityBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScoreAudienceQualityBatch not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) EstimatePrice(context.Context, *PriceRequest) (*PriceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimatePrice not implemented")
+}
+func (UnimplementedAnalyticsServiceServer) EstimatePriceBatch(context.Context, *PriceBatchRequest) (*PriceBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimatePriceBatch not implemented")
+}
 
 // UnsafeAnalyticsServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to AnalyticsServiceServer will
@@ -88,6 +206,122 @@ func _AnalyticsService_CalculateEngagement_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AnalyticsService_CalculateEngagementBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EngagementBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).CalculateEngagementBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/analytics.AnalyticsService/CalculateEngagementBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).CalculateEngagementBatch(ctx, req.(*EngagementBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_StreamEngagement_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AnalyticsServiceServer).StreamEngagement(&analyticsServiceStreamEngagementServer{stream})
+}
+
+type AnalyticsService_StreamEngagementServer interface {
+	Send(*EngagementResponse) error
+	Recv() (*EngagementRequest, error)
+	grpc.ServerStream
+}
+
+type analyticsServiceStreamEngagementServer struct {
+	grpc.ServerStream
+}
+
+func (x *analyticsServiceStreamEngagementServer) Send(m *EngagementResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *analyticsServiceStreamEngagementServer) Recv() (*EngagementRequest, error) {
+	m := new(EngagementRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AnalyticsService_ScoreAudienceQuality_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AudienceQualityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).ScoreAudienceQuality(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/analytics.AnalyticsService/ScoreAudienceQuality",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).ScoreAudienceQuality(ctx, req.(*AudienceQualityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_ScoreAudienceQualityBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AudienceQualityBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).ScoreAudienceQualityBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/analytics.AnalyticsService/ScoreAudienceQualityBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).ScoreAudienceQualityBatch(ctx, req.(*AudienceQualityBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_EstimatePrice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PriceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).EstimatePrice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/analytics.AnalyticsService/EstimatePrice",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).EstimatePrice(ctx, req.(*PriceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AnalyticsService_EstimatePriceBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PriceBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalyticsServiceServer).EstimatePriceBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/analytics.AnalyticsService/EstimatePriceBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalyticsServiceServer).EstimatePriceBatch(ctx, req.(*PriceBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AnalyticsService_ServiceDesc is the grpc.ServiceDesc for AnalyticsService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +333,34 @@ var AnalyticsService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CalculateEngagement",
 			Handler:    _AnalyticsService_CalculateEngagement_Handler,
 		},
+		{
+			MethodName: "CalculateEngagementBatch",
+			Handler:    _AnalyticsService_CalculateEngagementBatch_Handler,
+		},
+		{
+			MethodName: "ScoreAudienceQuality",
+			Handler:    _AnalyticsService_ScoreAudienceQuality_Handler,
+		},
+		{
+			MethodName: "ScoreAudienceQualityBatch",
+			Handler:    _AnalyticsService_ScoreAudienceQualityBatch_Handler,
+		},
+		{
+			MethodName: "EstimatePrice",
+			Handler:    _AnalyticsService_EstimatePrice_Handler,
+		},
+		{
+			MethodName: "EstimatePriceBatch",
+			Handler:    _AnalyticsService_EstimatePriceBatch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEngagement",
+			Handler:       _AnalyticsService_StreamEngagement_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/analytics.proto",
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/hammo/influScope/indexer/internal/repository"
+)
+
+// runDLQ lets operators look at and replay messages that exhausted their retries:
+//
+//	indexer dlq inspect [-limit N]
+//	indexer dlq replay [-limit N]
+func runDLQ(args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: indexer dlq <inspect|replay> [-limit N]")
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("dlq "+action, flag.ExitOnError)
+	limit := fs.Int("limit", 0, "maximum number of messages to handle (0 means all)")
+	_ = fs.Parse(args[1:])
+
+	ctx := context.Background()
+	dlq, err := repository.NewDeadLetterQueue(ctx, exchangeName, queueName, retryPolicy)
+	if err != nil {
+		log.Fatalf("Error connecting to RabbitMQ: %v", err)
+	}
+	defer dlq.Close()
+
+	switch action {
+	case "inspect":
+		letters, err := dlq.Inspect(ctx, *limit)
+		for i, l := range letters {
+			fmt.Printf("#%d attempts=%d error=%q\n%s\n\n", i+1, l.Attempts, l.LastError, l.Body)
+		}
+		if err != nil {
+			log.Fatalf("Inspecting %s failed: %v", dlq.Name(), err)
+		}
+		log.Printf("%d message(s) in %s left untouched", len(letters), dlq.Name())
+	case "replay":
+		replayed, err := dlq.Replay(ctx, *limit)
+		if err != nil {
+			log.Fatalf("Replay failed after %d message(s): %v", replayed, err)
+		}
+		log.Printf("Replayed %d message(s) from %s to %s", replayed, dlq.Name(), queueName)
+	default:
+		log.Fatalf("Unknown dlq action %q", action)
+	}
+}
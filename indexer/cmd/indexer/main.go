@@ -3,6 +3,11 @@ package main
 import (
 	"context"
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/hammo/influScope/indexer/internal/metrics"
 	"github.com/hammo/influScope/indexer/internal/repository"
@@ -13,9 +18,58 @@ const (
 	exchangeName = "influencer-events"
 	queueName    = "indexer-queue"
 	indexName    = "influencers"
+	metricsIndex = "influencer-metrics"
+	esAddress    = "http://elasticsearch:9200"
+
+	// Bulk indexing: flush after 500 profiles, 5MB of payload or 1s, whichever comes first
+	bulkMaxDocs   = 500
+	bulkMaxBytes  = 5 << 20
+	bulkMaxLinger = time.Second
+
+	// Failed messages are retried after 5s, 10s, 20s and 40s, then parked in influencer-events.dlq
+	retryMaxAttempts = 5
+	retryBaseDelay   = 5 * time.Second
+)
+
+// Defaults for the consumer tuning, overridable through INDEXER_WORKERS and INDEXER_PREFETCH.
+// The prefetch covers two bulk batches so workers keep filling the next one while a flush is in flight.
+const (
+	defaultWorkers  = 8
+	defaultPrefetch = 2 * bulkMaxDocs
+)
+
+// The analytics circuit opens after INDEXER_BREAKER_THRESHOLD consecutive failures (default 5)
+// and lets a trial call through every breakerCooldown. Meanwhile profiles are handled according
+// to INDEXER_ANALYTICS_FALLBACK: previous (default), scraper or retry.
+const (
+	defaultBreakerThreshold = 5
+	breakerCooldown         = 30 * time.Second
+	defaultFallback         = service.FallbackPreviousRate
 )
 
+// shutdownTimeout bounds draining on SIGTERM, below the pod's 30s termination grace period
+const shutdownTimeout = 20 * time.Second
+
+var retryPolicy = repository.RetryPolicy{MaxAttempts: retryMaxAttempts, BaseDelay: retryBaseDelay}
+
 func main() {
+	// Maintenance subcommands, e.g. `indexer reindex`, `indexer reenrich` or `indexer dlq inspect`
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "reindex":
+			runReindex(os.Args[2:])
+			return
+		case "reenrich":
+			runReenrich(os.Args[2:])
+			return
+		case "dlq":
+			runDLQ(os.Args[2:])
+			return
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+	}
+
 	ctx := context.Background()
 
 	// 1. Initialize Metrics
@@ -23,12 +77,20 @@ func main() {
 	go metricsSvc.StartServer(":8082")
 
 	// 2. Initialize Repositories
-	esRepo, err := repository.NewESRepository("http://elasticsearch:9200", indexName)
+	esRepo, err := repository.NewESRepository(esAddress, indexName, metricsIndex)
 	if err != nil {
 		log.Fatalf("Error connecting to ES: %v", err)
 	}
 	log.Println("Connected to Elasticsearch!")
 
+	// Create the index with its explicit mapping, or refuse to run against an incompatible one
+	if err := esRepo.EnsureIndex(ctx); err != nil {
+		log.Fatalf("Elasticsearch index check failed: %v", err)
+	}
+	if err := esRepo.EnsureMetricsIndex(ctx); err != nil {
+		log.Fatalf("Elasticsearch metrics index check failed: %v", err)
+	}
+
 	grpcRepo, err := repository.NewGRPCAnalyticsClient("analytics:50051")
 	if err != nil {
 		log.Fatalf("Error connecting to gRPC: %v", err)
@@ -36,14 +98,60 @@ func main() {
 	defer grpcRepo.Close()
 	log.Println("Connected to Analytics gRPC Service")
 
-	rmqRepo, err := repository.NewRabbitMQConsumer(ctx, exchangeName, queueName)
+	analytics := repository.NewCircuitBreaker(grpcRepo, repository.BreakerConfig{
+		FailureThreshold: envInt("INDEXER_BREAKER_THRESHOLD", defaultBreakerThreshold),
+		Cooldown:         breakerCooldown,
+	})
+
+	fallback := defaultFallback
+	if raw := os.Getenv("INDEXER_ANALYTICS_FALLBACK"); raw != "" {
+		if fallback, err = service.ParseFallbackMode(raw); err != nil {
+			log.Fatalf("Invalid INDEXER_ANALYTICS_FALLBACK: %v", err)
+		}
+	}
+
+	workers := envInt("INDEXER_WORKERS", defaultWorkers)
+	prefetch := envInt("INDEXER_PREFETCH", defaultPrefetch)
+
+	rmqRepo, err := repository.NewRabbitMQConsumer(ctx, exchangeName, queueName, prefetch, retryPolicy)
 	if err != nil {
 		log.Fatalf("Error connecting to RabbitMQ: %v", err)
 	}
 	defer rmqRepo.Close()
 	log.Println("Connected to RabbitMQ")
 
-	// 3. Initialize & Start Core Service
-	indexerSvc := service.NewIndexerService(rmqRepo, grpcRepo, esRepo, metricsSvc)
-	indexerSvc.Start(ctx)
+	bulkRepo := repository.NewBulkIndexer(esRepo, repository.BulkConfig{
+		MaxDocs:   bulkMaxDocs,
+		MaxBytes:  bulkMaxBytes,
+		MaxLinger: bulkMaxLinger,
+	})
+
+	// 3. Initialize & Start Core Service, until Kubernetes (or Ctrl+C) asks us to stop
+	indexerSvc := service.NewIndexerService(rmqRepo, analytics, bulkRepo, metricsSvc, workers, fallback)
+
+	runCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	indexerSvc.Start(runCtx)
+
+	// 4. Drain in-flight messages and flush pending writes, then the deferred
+	// calls close the AMQP and gRPC connections
+	shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	defer cancel()
+	if err := indexerSvc.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown incomplete: %v", err)
+	}
+	log.Println("Indexer stopped")
+}
+
+// envInt reads a positive integer setting, falling back to def when unset
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		log.Fatalf("Invalid %s %q: expected a positive integer", key, raw)
+	}
+	return v
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/hammo/influScope/indexer/internal/repository"
+	"github.com/hammo/influScope/indexer/internal/service"
+)
+
+// maxAnalyticsBatch is the largest batch analytics accepts in one call
+const maxAnalyticsBatch = 500
+
+// runReenrich re-scores the stored profiles through analytics, e.g. after a model change:
+//
+//	indexer reenrich [-batch N] [-rate N] [-checkpoint FILE]
+//	indexer reenrich -dry-run
+//
+// Progress is saved to the checkpoint file after every batch and an interrupted run picks up
+// from it; the file is removed once the whole index is done. A dry run scores everything
+// without writing and prints how the engagement rate distribution would shift.
+func runReenrich(args []string) {
+	fs := flag.NewFlagSet("reenrich", flag.ExitOnError)
+	batch := fs.Int("batch", 200, "profiles per analytics call and update")
+	rate := fs.Float64("rate", 500, "maximum profiles per second (0 means unthrottled)")
+	checkpointPath := fs.String("checkpoint", "reenrich-checkpoint.json", "file recording progress, to resume an interrupted run")
+	dryRun := fs.Bool("dry-run", false, "score without writing and report the distribution shift")
+	_ = fs.Parse(args)
+	if *batch < 1 || *batch > maxAnalyticsBatch {
+		log.Fatalf("Invalid -batch %d: expected 1 to %d, the most analytics scores in one call", *batch, maxAnalyticsBatch)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	esRepo, err := repository.NewESRepository(esAddress, indexName, metricsIndex)
+	if err != nil {
+		log.Fatalf("Error connecting to ES: %v", err)
+	}
+	grpcRepo, err := repository.NewGRPCAnalyticsClient("analytics:50051")
+	if err != nil {
+		log.Fatalf("Error connecting to gRPC: %v", err)
+	}
+	defer grpcRepo.Close()
+
+	// A dry run always covers the whole index
+	var from service.Checkpoint
+	if !*dryRun {
+		if from, err = loadCheckpoint(*checkpointPath); err != nil {
+			log.Fatalf("Reading checkpoint failed: %v", err)
+		}
+		if from.LastID != "" {
+			log.Printf("Resuming after %s (%d profiles scanned so far)", from.LastID, from.Scanned)
+		}
+	}
+
+	reenricher := service.NewReenricher(esRepo, grpcRepo, service.ReenrichConfig{BatchSize: *batch, Rate: *rate, DryRun: *dryRun})
+	report, err := reenricher.Run(ctx, from, func(c service.Checkpoint) error {
+		return saveCheckpoint(*checkpointPath, c)
+	})
+	printReport(report, *dryRun)
+	if err != nil {
+		log.Fatalf("Re-enrichment stopped: %v", err)
+	}
+
+	if !*dryRun {
+		if err := os.Remove(*checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Removing checkpoint failed: %v", err)
+		}
+		log.Printf("Re-enriched %d profiles, %d skipped as rewritten meanwhile", report.Updated, report.Conflicts)
+	}
+}
+
+func loadCheckpoint(path string) (service.Checkpoint, error) {
+	var c service.Checkpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
+
+// saveCheckpoint replaces the file atomically, a crash mid-write keeps the previous checkpoint
+func saveCheckpoint(path string, c service.Checkpoint) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func printReport(report *service.ReenrichReport, dryRun bool) {
+	if dryRun {
+		fmt.Printf("Dry run: %d profiles scored, %d rates would change\n\n", report.Scanned, report.Changed)
+	} else {
+		fmt.Printf("%d profiles scored in this run, %d rates changed\n\n", report.Before.Total, report.Changed)
+	}
+
+	fmt.Printf("%-12s %8s %8s\n", "rate (%)", "before", "after")
+	for i := range report.Before.Counts {
+		fmt.Printf("%-12s %8d %8d\n", bucketLabel(i), report.Before.Counts[i], report.After.Counts[i])
+	}
+	fmt.Printf("%-12s %8.2f %8.2f\n\n", "mean", report.Before.Mean(), report.After.Mean())
+
+	transitions := make([]string, 0, len(report.Versions))
+	for t := range report.Versions {
+		transitions = append(transitions, t)
+	}
+	sort.Strings(transitions)
+	for _, t := range transitions {
+		fmt.Printf("model %s: %d\n", t, report.Versions[t])
+	}
+}
+
+// bucketLabel names a histogram bucket of service.RateBuckets, e.g. "2-3"
+func bucketLabel(i int) string {
+	bounds := service.RateBuckets
+	switch {
+	case i == 0:
+		return fmt.Sprintf("<%g", bounds[0])
+	case i == len(bounds):
+		return fmt.Sprintf(">=%g", bounds[i-1])
+	default:
+		return fmt.Sprintf("%g-%g", bounds[i-1], bounds[i])
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hammo/influScope/indexer/internal/repository"
+)
+
+// runReindex copies the index behind the read alias into the versioned index of the
+// current mapping and swaps the alias, so a mapping change never takes search offline.
+func runReindex(args []string) {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	deleteOld := fs.Bool("delete-old", false, "delete the previous versioned index after the alias swap")
+	_ = fs.Parse(args)
+
+	esRepo, err := repository.NewESRepository(esAddress, indexName, metricsIndex)
+	if err != nil {
+		log.Fatalf("Error connecting to ES: %v", err)
+	}
+
+	result, err := esRepo.Reindex(context.Background(), *deleteOld)
+	if err != nil {
+		log.Fatalf("Reindex failed: %v", err)
+	}
+	log.Printf("Reindexed %s -> %s: %d copied, %d already up to date", result.Source, result.Destination, result.Copied, result.Skipped)
+}
@@ -10,6 +10,12 @@ import (
 type Message interface {
 	Body() []byte
 	Ack() error
+	// Nack hands a failed message back to the broker, which retries it later
+	// or dead-letters it once its attempts are exhausted
+	Nack(reason error) error
+	// Requeue puts the message straight back on the queue without counting an attempt,
+	// for messages left unprocessed on shutdown
+	Requeue() error
 }
 
 // MessageConsumer handles pulling messages from the broker
@@ -18,15 +24,60 @@ type MessageConsumer interface {
 	Close() error
 }
 
+// Engagement is a profile's score along with the version of the model that computed it
+type Engagement struct {
+	Rate         float64
+	ModelVersion string
+}
+
+// AudienceQuality is a 0 (fake) to 100 (authentic) score and the codes of the signals that lowered it
+type AudienceQuality struct {
+	Score int
+	Flags []string
+}
+
+// Prices are the fee estimates of a profile, keyed by content type
+type Prices struct {
+	Currency string
+	ByType   map[string]models.PriceRange
+}
+
 // AnalyticsClient handles gRPC requests
 type AnalyticsClient interface {
-	GetEngagement(ctx context.Context, username string, followers int, platform string) (float64, error)
+	GetEngagement(ctx context.Context, profile *models.Influencer) (Engagement, error)
+	// GetEngagementBatch scores several profiles in one round trip, scores[i] belongs to profiles[i]
+	GetEngagementBatch(ctx context.Context, profiles []*models.Influencer) ([]Engagement, error)
+	// GetAudienceQualityBatch scores how genuine each audience is, using the profiles' engagement rates
+	GetAudienceQualityBatch(ctx context.Context, profiles []*models.Influencer) ([]AudienceQuality, error)
+	// GetPriceBatch estimates sponsored post fees, using the profiles' engagement rates
+	GetPriceBatch(ctx context.Context, profiles []*models.Influencer) ([]Prices, error)
 	Close() error
 }
 
-// SearchRepository handles saving to Elasticsearch
+// SearchRepository handles saving to Elasticsearch.
+// Profiles are batched, so the outcome of each one is reported later through done.
 type SearchRepository interface {
-	IndexProfile(ctx context.Context, profile *models.Influencer) error
+	IndexProfile(ctx context.Context, profile *models.Influencer, done func(error))
+	Flush(ctx context.Context) error
+	// GetProfiles returns the stored documents of the given influencer IDs, keyed by ID
+	GetProfiles(ctx context.Context, ids []string) (map[string]*models.Influencer, error)
+}
+
+// StoredProfile is a profile read back from the index. SeqNo and PrimaryTerm identify the
+// revision that was read, so an update can be rejected if the document changed since.
+type StoredProfile struct {
+	Profile     *models.Influencer
+	SeqNo       int64
+	PrimaryTerm int64
+}
+
+// ProfileStore gives maintenance jobs access to the indexed profiles
+type ProfileStore interface {
+	// ScanProfiles returns up to size profiles ordered by ID, starting after afterID ("" for the first page)
+	ScanProfiles(ctx context.Context, afterID string, size int) ([]StoredProfile, error)
+	// UpdateEnrichment rewrites the analytics fields of the profiles. Documents written since they
+	// were read are left alone and counted as conflicts.
+	UpdateEnrichment(ctx context.Context, profiles []StoredProfile) (conflicts int, err error)
 }
 
 // MetricsTracker handles Prometheus counters
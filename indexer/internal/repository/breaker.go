@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrCircuitOpen is returned without calling analytics while the breaker is open
+var ErrCircuitOpen = errors.New("analytics circuit breaker is open")
+
+// BreakerConfig controls when the breaker opens and how long it stays open
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the circuit
+	Cooldown         time.Duration // Time before a single trial call is let through
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops calling analytics after repeated failures, so an outage costs the
+// indexer nothing instead of a timeout per batch. Once the cooldown has passed one call is
+// let through: if it succeeds the circuit closes, otherwise it opens for another cooldown.
+type circuitBreaker struct {
+	client domain.AnalyticsClient
+	cfg    BreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewCircuitBreaker(client domain.AnalyticsClient, cfg BreakerConfig) *circuitBreaker {
+	return &circuitBreaker{client: client, cfg: cfg, now: time.Now}
+}
+
+func (b *circuitBreaker) GetEngagement(ctx context.Context, profile *models.Influencer) (domain.Engagement, error) {
+	if err := b.allow(); err != nil {
+		return domain.Engagement{}, err
+	}
+	score, err := b.client.GetEngagement(ctx, profile)
+	b.record(err)
+	return score, err
+}
+
+func (b *circuitBreaker) GetEngagementBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Engagement, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	scores, err := b.client.GetEngagementBatch(ctx, profiles)
+	b.record(err)
+	return scores, err
+}
+
+func (b *circuitBreaker) GetAudienceQualityBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.AudienceQuality, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	qualities, err := b.client.GetAudienceQualityBatch(ctx, profiles)
+	b.record(err)
+	return qualities, err
+}
+
+func (b *circuitBreaker) GetPriceBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Prices, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	prices, err := b.client.GetPriceBatch(ctx, profiles)
+	b.record(err)
+	return prices, err
+}
+
+func (b *circuitBreaker) Close() error {
+	return b.client.Close()
+}
+
+// allow fails fast while the circuit is open, and lets a single trial call through once the
+// cooldown has passed
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cfg.Cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+		return nil
+	case breakerHalfOpen:
+		// The trial call is still in flight
+		return ErrCircuitOpen
+	default:
+		return nil
+	}
+}
+
+// record updates the circuit with the outcome of a call. Only a success proves analytics is
+// healthy; other errors that are not an outage, e.g. a trial cancelled by shutdown, tell nothing
+// either way and leave the failure count alone.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != breakerClosed {
+			log.Println("Analytics is back, closing the circuit breaker")
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	if !isOutage(err) {
+		if b.state == breakerHalfOpen {
+			// Inconclusive trial: back to open without restarting the cooldown, so the next
+			// call is the new trial
+			b.state = breakerOpen
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		if b.state != breakerOpen {
+			log.Printf("Opening the analytics circuit breaker for %s after %d failures: %v", b.cfg.Cooldown, b.failures, err)
+		}
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// isOutage tells whether err means analytics is unhealthy. Errors about the request itself
+// (e.g. Unimplemented from an older version mid-rollout) or a caller giving up do not count.
+func isOutage(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return err != nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyAnalytics fails every call with err and counts the calls that reached it
+type flakyAnalytics struct {
+	err   error
+	calls int
+}
+
+func (f *flakyAnalytics) GetEngagement(ctx context.Context, p *models.Influencer) (domain.Engagement, error) {
+	f.calls++
+	return domain.Engagement{Rate: 1}, f.err
+}
+func (f *flakyAnalytics) GetEngagementBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Engagement, error) {
+	f.calls++
+	return make([]domain.Engagement, len(profiles)), f.err
+}
+func (f *flakyAnalytics) GetAudienceQualityBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.AudienceQuality, error) {
+	f.calls++
+	return make([]domain.AudienceQuality, len(profiles)), f.err
+}
+func (f *flakyAnalytics) GetPriceBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Prices, error) {
+	f.calls++
+	return make([]domain.Prices, len(profiles)), f.err
+}
+func (f *flakyAnalytics) Close() error { return nil }
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	client := &flakyAnalytics{err: status.Error(codes.Unavailable, "connection refused")}
+	breaker := NewCircuitBreaker(client, BreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.GetEngagementBatch(context.Background(), nil); status.Code(err) != codes.Unavailable {
+			t.Fatalf("Call %d: expected the analytics error, got %v", i, err)
+		}
+	}
+
+	// Open: fails fast without reaching analytics, whatever the method
+	if _, err := breaker.GetPriceBatch(context.Background(), nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected 3 calls to reach analytics, got %d", client.calls)
+	}
+}
+
+func TestBreakerClosesAfterSuccessfulTrial(t *testing.T) {
+	client := &flakyAnalytics{err: status.Error(codes.DeadlineExceeded, "timeout")}
+	breaker := NewCircuitBreaker(client, BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	breaker.GetEngagementBatch(context.Background(), nil)
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to be open, got %v", err)
+	}
+
+	// After the cooldown a failing trial opens it again
+	now = now.Add(time.Minute)
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("Expected the trial to reach analytics, got %v", err)
+	}
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected a failed trial to reopen the circuit, got %v", err)
+	}
+
+	// A successful trial closes it
+	client.err = nil
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.GetEngagementBatch(context.Background(), nil); err != nil {
+			t.Fatalf("Call %d: expected the circuit to be closed, got %v", i, err)
+		}
+	}
+}
+
+func TestBreakerIgnoresRequestErrors(t *testing.T) {
+	// An analytics version without the RPC is not an outage
+	client := &flakyAnalytics{err: status.Error(codes.Unimplemented, "unknown method")}
+	breaker := NewCircuitBreaker(client, BreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.GetAudienceQualityBatch(context.Background(), nil); status.Code(err) != codes.Unimplemented {
+			t.Fatalf("Call %d: expected Unimplemented, got %v", i, err)
+		}
+	}
+	if client.calls != 3 {
+		t.Errorf("Expected every call to reach analytics, got %d", client.calls)
+	}
+}
+
+func TestBreakerInconclusiveTrialKeepsCircuitOpen(t *testing.T) {
+	client := &flakyAnalytics{err: status.Error(codes.Unavailable, "connection refused")}
+	breaker := NewCircuitBreaker(client, BreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	breaker.GetEngagementBatch(context.Background(), nil)
+	breaker.GetEngagementBatch(context.Background(), nil)
+
+	// The trial is cancelled, e.g. by shutdown: that says nothing about analytics
+	client.err = status.Error(codes.Canceled, "context canceled")
+	now = now.Add(time.Minute)
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); status.Code(err) != codes.Canceled {
+		t.Fatalf("Expected the trial to reach analytics, got %v", err)
+	}
+
+	// Still not closed: the next call is a trial, and one failure is enough to reopen the circuit
+	client.err = status.Error(codes.Unavailable, "connection refused")
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected another trial call, got %v", err)
+	}
+	if _, err := breaker.GetEngagementBatch(context.Background(), nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the circuit to be open, got %v", err)
+	}
+	if client.calls != 4 {
+		t.Errorf("Expected 4 calls to reach analytics, got %d", client.calls)
+	}
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// BulkConfig controls when a batch of queued profiles is sent to Elasticsearch.
+// Whichever limit is reached first triggers the flush.
+type BulkConfig struct {
+	MaxDocs   int           // Number of queued profiles
+	MaxBytes  int           // Size of the NDJSON payload
+	MaxLinger time.Duration // Age of the oldest queued profile
+}
+
+type bulkItem struct {
+	payload []byte // Action and source lines
+	actions int    // Number of actions in payload, each gets an entry in the response
+	done    func(error)
+}
+
+// bulkIndexer batches profiles into _bulk requests and reports every item's outcome
+// through its done callback, so callers can ack exactly the messages that were stored.
+type bulkIndexer struct {
+	es  *esRepository
+	cfg BulkConfig
+
+	mu    sync.Mutex // Guards the pending batch
+	items []bulkItem
+	size  int
+	timer *time.Timer
+
+	sendMu sync.Mutex // Keeps batches in the order they were filled
+}
+
+func NewBulkIndexer(es *esRepository, cfg BulkConfig) *bulkIndexer {
+	return &bulkIndexer{es: es, cfg: cfg}
+}
+
+// IndexProfile queues the profile, and a snapshot of its metrics when a metrics index is
+// configured, for the next bulk request. When the batch is full the flush happens inline,
+// which slows the caller down instead of buffering without bound.
+func (b *bulkIndexer) IndexProfile(ctx context.Context, profile *models.Influencer, done func(error)) {
+	payload, err := bulkPayload(b.es.writeTarget(), profile)
+	if err != nil {
+		done(err)
+		return
+	}
+	actions := 1
+
+	// Both writes share the message's outcome, a retry redoes both idempotently
+	if b.es.metricsIndex != "" && profile.ID != "" {
+		snapshot, err := snapshotPayload(b.es.metricsIndex, profile)
+		if err != nil {
+			done(err)
+			return
+		}
+		payload = append(payload, snapshot...)
+		actions++
+	}
+
+	b.mu.Lock()
+	b.items = append(b.items, bulkItem{payload: payload, actions: actions, done: done})
+	b.size += len(payload)
+	if len(b.items) == 1 && b.cfg.MaxLinger > 0 {
+		b.timer = time.AfterFunc(b.cfg.MaxLinger, func() {
+			if err := b.Flush(context.Background()); err != nil {
+				log.Printf("Bulk flush failed: %v", err)
+			}
+		})
+	}
+	full := len(b.items) >= b.cfg.MaxDocs || b.size >= b.cfg.MaxBytes
+	b.mu.Unlock()
+
+	if full {
+		if err := b.Flush(ctx); err != nil {
+			log.Printf("Bulk flush failed: %v", err)
+		}
+	}
+}
+
+// GetProfiles reads the stored documents, profiles still waiting in a batch are not seen
+func (b *bulkIndexer) GetProfiles(ctx context.Context, ids []string) (map[string]*models.Influencer, error) {
+	return b.es.GetProfiles(ctx, ids)
+}
+
+// Flush sends whatever is queued. A request-level error is returned and also handed to
+// every item's callback; per-item failures only reach their own callback.
+func (b *bulkIndexer) Flush(ctx context.Context) error {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Lock()
+	items := b.items
+	b.items, b.size = nil, 0
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	errs, err := b.send(ctx, items)
+	if err != nil {
+		for _, item := range items {
+			item.done(err)
+		}
+		return err
+	}
+
+	for i, item := range items {
+		item.done(errs[i])
+	}
+	return nil
+}
+
+// send issues one _bulk request and returns the outcome of each item, in order
+func (b *bulkIndexer) send(ctx context.Context, items []bulkItem) ([]error, error) {
+	var buf bytes.Buffer
+	actions := 0
+	for _, item := range items {
+		buf.Write(item.payload)
+		actions += item.actions
+	}
+
+	res, err := b.es.client.Bulk(bytes.NewReader(buf.Bytes()), b.es.client.Bulk.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("bulk request failed: %s", res.String())
+	}
+
+	var body struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding bulk response failed: %w", err)
+	}
+	if len(body.Items) != actions {
+		return nil, fmt.Errorf("bulk response has %d items, sent %d", len(body.Items), actions)
+	}
+
+	errs := make([]error, len(items))
+	next := 0
+	for i, item := range items {
+		// An item fails with the first of its actions that failed
+		for _, result := range body.Items[next : next+item.actions] {
+			// Each entry is keyed by its action ("index" or "create")
+			for _, r := range result {
+				if errs[i] != nil {
+					continue
+				}
+				if r.Status == http.StatusConflict && r.Error != nil && r.Error.Type == "version_conflict_engine_exception" {
+					// A newer (or the same) version is already stored: the write is stale or a replay, not failed
+					log.Printf("Skipped already applied write: %s", r.Error.Reason)
+					continue
+				}
+				if r.Error != nil {
+					errs[i] = fmt.Errorf("indexing failed (%d): %s: %s", r.Status, r.Error.Type, r.Error.Reason)
+				} else if r.Status >= 300 {
+					errs[i] = fmt.Errorf("indexing failed with status %d", r.Status)
+				}
+			}
+		}
+		next += item.actions
+	}
+	return errs, nil
+}
+
+// bulkPayload renders the action and source lines for one profile
+func bulkPayload(index string, profile *models.Influencer) ([]byte, error) {
+	source, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := map[string]interface{}{"_index": index}
+	// Use the influencer ID as the document _id so the API can look profiles up directly
+	if profile.ID != "" {
+		meta["_id"] = profile.ID
+		// External versioning makes Elasticsearch reject an event older than the stored document.
+		// Events without a timestamp fall back to last write wins.
+		if !profile.ScrapedAt.IsZero() {
+			meta["version"] = profile.ScrapedAt.UnixNano()
+			meta["version_type"] = "external"
+		}
+	}
+	action, err := json.Marshal(map[string]interface{}{"index": meta})
+	if err != nil {
+		return nil, err
+	}
+
+	return ndjson(action, source), nil
+}
+
+// ndjson joins an action and its source into newline terminated bulk lines
+func ndjson(action, source []byte) []byte {
+	payload := make([]byte, 0, len(action)+len(source)+2)
+	payload = append(payload, action...)
+	payload = append(payload, '\n')
+	payload = append(payload, source...)
+	payload = append(payload, '\n')
+	return payload
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/upfluence/amqp"
+	"github.com/upfluence/amqp/amqputil"
+)
+
+// dlqIdleTimeout is how long inspect/replay wait for the next dead letter before
+// assuming the queue is drained
+const dlqIdleTimeout = 2 * time.Second
+
+// DeadLetter is a message parked in the DLQ after exhausting its retries
+type DeadLetter struct {
+	Body      []byte
+	Attempts  int
+	LastError string
+}
+
+// deadLetterQueue reads and replays <exchange>.dlq for operators
+type deadLetterQueue struct {
+	broker   amqp.Broker
+	topology topology
+}
+
+func NewDeadLetterQueue(ctx context.Context, exchange, queue string, policy RetryPolicy) (*deadLetterQueue, error) {
+	broker := amqputil.Open()
+	t := newTopology(exchange, queue)
+
+	// Declaring is idempotent and lets the commands run before any indexer has started
+	if err := t.declare(ctx, broker, policy); err != nil {
+		broker.Close()
+		return nil, err
+	}
+	return &deadLetterQueue{broker: broker, topology: t}, nil
+}
+
+func (q *deadLetterQueue) Name() string { return q.topology.dlq }
+
+// Inspect returns up to limit dead letters without removing them from the queue.
+// They are held unacked while reading so none is seen twice, then requeued.
+func (q *deadLetterQueue) Inspect(ctx context.Context, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	err := q.drain(ctx, limit, func(consumer amqp.Consumer, d *amqp.Delivery) error {
+		letters = append(letters, DeadLetter{
+			Body:      d.Message.Body,
+			Attempts:  headerInt(d.Message.Headers, retryCountHeader) + 1,
+			LastError: headerString(d.Message.Headers, lastErrorHeader),
+		})
+		return nil
+	}, func(consumer amqp.Consumer, tags []uint64) error {
+		for _, tag := range tags {
+			if err := consumer.Nack(ctx, tag, amqp.NackOptions{Requeue: true}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return letters, err
+}
+
+// Replay moves up to limit dead letters back onto the work queue with a fresh retry budget.
+// Each one is only acked once it has been republished.
+func (q *deadLetterQueue) Replay(ctx context.Context, limit int) (int, error) {
+	replayed := 0
+	err := q.drain(ctx, limit, func(consumer amqp.Consumer, d *amqp.Delivery) error {
+		msg := d.Message
+		msg.Headers = copyHeaders(msg.Headers)
+		delete(msg.Headers, retryCountHeader)
+		delete(msg.Headers, lastErrorHeader)
+
+		// The default exchange routes by queue name, so only the indexer gets the replay
+		if err := q.broker.Publish(ctx, "", q.topology.queue, msg, amqp.PublishOptions{}); err != nil {
+			return fmt.Errorf("republish failed: %w", err)
+		}
+		if err := consumer.Ack(ctx, d.DeliveryTag, amqp.AckOptions{}); err != nil {
+			return err
+		}
+		replayed++
+		return nil
+	}, nil)
+	return replayed, err
+}
+
+// drain consumes the DLQ until it is idle or limit messages (0 means all) were handled.
+// release is called with the tags of every handled delivery before the consumer closes.
+func (q *deadLetterQueue) drain(
+	ctx context.Context,
+	limit int,
+	handle func(amqp.Consumer, *amqp.Delivery) error,
+	release func(amqp.Consumer, []uint64) error,
+) error {
+	consumer, err := q.broker.Consume(ctx, q.topology.dlq, amqp.ConsumeOptions{AutoACK: false})
+	if err != nil {
+		return fmt.Errorf("consume %s failed: %w", q.topology.dlq, err)
+	}
+	defer consumer.Close()
+
+	var tags []uint64
+	for limit <= 0 || len(tags) < limit {
+		nextCtx, cancel := context.WithTimeout(ctx, dlqIdleTimeout)
+		delivery, err := consumer.Next(nextCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				break // Queue drained
+			}
+			return err
+		}
+
+		tags = append(tags, delivery.DeliveryTag)
+		if err := handle(consumer, delivery); err != nil {
+			return err
+		}
+	}
+
+	if release != nil {
+		return release(consumer, tags)
+	}
+	return nil
+}
+
+func (q *deadLetterQueue) Close() error {
+	return q.broker.Close()
+}
+
+func headerString(headers map[string]any, key string) string {
+	s, _ := headers[key].(string)
+	return s
+}
@@ -12,11 +12,13 @@ import (
 )
 
 type esRepository struct {
-	client    *elasticsearch.Client
-	indexName string
+	client       *elasticsearch.Client
+	indexName    string // Read alias queried by the API
+	writeIndex   string // Versioned index this indexer writes to, set by EnsureIndex
+	metricsIndex string // Time-series index of metric snapshots, disabled when empty
 }
 
-func NewESRepository(address, index string) (*esRepository, error) {
+func NewESRepository(address, index, metricsIndex string) (*esRepository, error) {
 	cfg := elasticsearch.Config{
 		Addresses: []string{address},
 	}
@@ -30,7 +32,7 @@ func NewESRepository(address, index string) (*esRepository, error) {
 		res, err := es.Info()
 		if err == nil && res.StatusCode == 200 {
 			res.Body.Close()
-			return &esRepository{client: es, indexName: index}, nil
+			return &esRepository{client: es, indexName: index, metricsIndex: metricsIndex}, nil
 		}
 		time.Sleep(3 * time.Second)
 	}
@@ -38,25 +40,53 @@ func NewESRepository(address, index string) (*esRepository, error) {
 	return nil, fmt.Errorf("failed to connect to elasticsearch after retries")
 }
 
-func (r *esRepository) IndexProfile(ctx context.Context, profile *models.Influencer) error {
-	body, err := json.Marshal(profile)
-	if err != nil {
-		return err
+// writeTarget falls back to the alias when EnsureIndex has not resolved a versioned index
+func (r *esRepository) writeTarget() string {
+	if r.writeIndex != "" {
+		return r.writeIndex
+	}
+	return r.indexName
+}
+
+// GetProfiles fetches the stored documents of the given influencer IDs in one _mget request.
+// IDs that were never indexed are missing from the result.
+func (r *esRepository) GetProfiles(ctx context.Context, ids []string) (map[string]*models.Influencer, error) {
+	profiles := make(map[string]*models.Influencer, len(ids))
+	if len(ids) == 0 {
+		return profiles, nil
 	}
 
-	res, err := r.client.Index(
-		r.indexName,
-		bytes.NewReader(body),
-		r.client.Index.WithRefresh("true"),
-		r.client.Index.WithContext(ctx),
+	body, err := json.Marshal(map[string][]string{"ids": ids})
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.client.Mget(bytes.NewReader(body),
+		r.client.Mget.WithContext(ctx),
+		r.client.Mget.WithIndex(r.indexName),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("indexing failed: %s", res.String())
+		return nil, fmt.Errorf("mget request failed: %s", res.String())
+	}
+
+	var result struct {
+		Docs []struct {
+			ID     string             `json:"_id"`
+			Found  bool               `json:"found"`
+			Source *models.Influencer `json:"_source"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding mget response failed: %w", err)
+	}
+	for _, doc := range result.Docs {
+		if doc.Found && doc.Source != nil {
+			profiles[doc.ID] = doc.Source
+		}
 	}
-	return nil
+	return profiles, nil
 }
@@ -3,13 +3,17 @@ package repository
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/hammo/influScope/indexer/internal/domain"
 	"github.com/hammo/influScope/pkg/models"
 )
 
@@ -17,6 +21,9 @@ import (
 type MockElasticsearchTransport struct {
 	responses []MockResponse
 	callCount int
+	lastPath  string
+	lastBody  string
+	bodies    map[string]string // Last body sent to each path
 	mu        sync.Mutex
 }
 
@@ -43,6 +50,16 @@ func (m *MockElasticsearchTransport) RoundTrip(req *http.Request) (*http.Respons
 		}, nil
 	}
 
+	m.lastPath = req.URL.Path
+	if req.Body != nil {
+		body, _ := io.ReadAll(req.Body)
+		m.lastBody = string(body)
+		if m.bodies == nil {
+			m.bodies = make(map[string]string)
+		}
+		m.bodies[req.URL.Path] = m.lastBody
+	}
+
 	if m.callCount >= len(m.responses) {
 		m.callCount++
 		return &http.Response{
@@ -66,23 +83,123 @@ func (m *MockElasticsearchTransport) RoundTrip(req *http.Request) (*http.Respons
 	}, nil
 }
 
+// collectOutcome returns a done callback and the slice it records into
+func collectOutcome() (func(error), *[]error) {
+	var outcomes []error
+	return func(err error) { outcomes = append(outcomes, err) }, &outcomes
+}
+
 func TestSuccessfulIndexing(t *testing.T) {
 	mockTransport := &MockElasticsearchTransport{
-		responses: []MockResponse{{statusCode: 200, body: `{"result":"created"}`}},
+		responses: []MockResponse{{statusCode: 200, body: `{"errors":false,"items":[{"index":{"_id":"abc-123","status":201}}]}`}},
 	}
 
 	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
 	repo := &esRepository{client: esClient, indexName: "test-index"}
+	bulk := NewBulkIndexer(repo, BulkConfig{MaxDocs: 10, MaxBytes: 1 << 20, MaxLinger: time.Hour})
 
-	profile := &models.Influencer{Username: "testuser", Followers: 1000}
-	err := repo.IndexProfile(context.Background(), profile)
+	done, outcomes := collectOutcome()
+	profile := &models.Influencer{ID: "abc-123", Username: "testuser", Followers: 1000}
+	bulk.IndexProfile(context.Background(), profile, done)
 
-	if err != nil {
+	if mockTransport.callCount != 0 {
+		t.Fatalf("Expected the profile to wait for the batch to fill, got %d calls", mockTransport.callCount)
+	}
+	if err := bulk.Flush(context.Background()); err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
+
+	if len(*outcomes) != 1 || (*outcomes)[0] != nil {
+		t.Fatalf("Expected one successful outcome, got %v", *outcomes)
+	}
 	if mockTransport.callCount != 1 {
 		t.Errorf("Expected 1 Elasticsearch call, got %d", mockTransport.callCount)
 	}
+	if mockTransport.lastPath != "/_bulk" {
+		t.Errorf("Expected a _bulk request, got path %s", mockTransport.lastPath)
+	}
+	// Documents are stored under their influencer ID so the API can look them up
+	if !strings.Contains(mockTransport.lastBody, `"_id":"abc-123"`) {
+		t.Errorf("Expected the influencer ID as document _id, got %s", mockTransport.lastBody)
+	}
+}
+
+func TestGetProfilesReadsStoredDocuments(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"docs":[
+			{"_id":"1","found":true,"_source":{"id":"1","engagement_rate":3.3,"analytics_model_version":"rules-v1"}},
+			{"_id":"2","found":false}
+		]}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	profiles, err := repo.GetProfiles(context.Background(), []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockTransport.lastPath != "/test-index/_mget" {
+		t.Errorf("Expected an _mget on the read alias, got path %s", mockTransport.lastPath)
+	}
+	if len(profiles) != 1 || profiles["1"].EngagementRate != 3.3 || profiles["1"].AnalyticsModelVersion != "rules-v1" {
+		t.Errorf("Expected only the stored profile, got %v", profiles)
+	}
+}
+
+func TestScanProfilesPagesByID(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"hits":{"hits":[
+			{"_id":"b","_seq_no":7,"_primary_term":1,"_source":{"id":"b","engagement_rate":2.5}}
+		]}}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	profiles, err := repo.ScanProfiles(context.Background(), "a", 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockTransport.lastPath != "/test-index/_search" {
+		t.Errorf("Expected a search on the read alias, got path %s", mockTransport.lastPath)
+	}
+	if !strings.Contains(mockTransport.lastBody, `"search_after":["a"]`) || !strings.Contains(mockTransport.lastBody, `"seq_no_primary_term":true`) {
+		t.Errorf("Expected to continue after the last ID with revisions, got %s", mockTransport.lastBody)
+	}
+	if len(profiles) != 1 || profiles[0].Profile.ID != "b" || profiles[0].SeqNo != 7 || profiles[0].PrimaryTerm != 1 {
+		t.Errorf("Expected profile b at revision 7/1, got %+v", profiles)
+	}
+}
+
+func TestUpdateEnrichmentSkipsRewrittenDocuments(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"errors":true,"items":[
+			{"update":{"_id":"a","status":200}},
+			{"update":{"_id":"b","status":409,"error":{"type":"version_conflict_engine_exception","reason":"seq_no mismatch"}}}
+		]}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	conflicts, err := repo.UpdateEnrichment(context.Background(), []domain.StoredProfile{
+		{Profile: &models.Influencer{ID: "a", Username: "alice", EngagementRate: 4.2, EnrichmentStatus: models.EnrichmentComplete}, SeqNo: 3, PrimaryTerm: 1},
+		{Profile: &models.Influencer{ID: "b", EngagementRate: 1.1}, SeqNo: 5, PrimaryTerm: 1},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if conflicts != 1 {
+		t.Errorf("Expected 1 conflict, got %d", conflicts)
+	}
+	if !strings.Contains(mockTransport.lastBody, `"if_seq_no":3`) || !strings.Contains(mockTransport.lastBody, `"engagement_rate":4.2`) {
+		t.Errorf("Expected conditional partial updates, got %s", mockTransport.lastBody)
+	}
+	// Only the analytics fields are written
+	if strings.Contains(mockTransport.lastBody, "alice") {
+		t.Errorf("Expected the scraper's fields to be left alone, got %s", mockTransport.lastBody)
+	}
 }
 
 func TestIndexingWithNetworkError(t *testing.T) {
@@ -92,11 +209,369 @@ func TestIndexingWithNetworkError(t *testing.T) {
 
 	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
 	repo := &esRepository{client: esClient, indexName: "test-index"}
+	bulk := NewBulkIndexer(repo, BulkConfig{MaxDocs: 10, MaxBytes: 1 << 20, MaxLinger: time.Hour})
 
-	profile := &models.Influencer{Username: "testuser"}
-	err := repo.IndexProfile(context.Background(), profile)
+	done, outcomes := collectOutcome()
+	bulk.IndexProfile(context.Background(), &models.Influencer{Username: "testuser"}, done)
+	err := bulk.Flush(context.Background())
 
 	if !errors.Is(err, context.DeadlineExceeded) {
 		t.Errorf("Expected DeadlineExceeded error, got %v", err)
 	}
+	// Every queued item hears about a failed request
+	if len(*outcomes) != 1 || !errors.Is((*outcomes)[0], context.DeadlineExceeded) {
+		t.Errorf("Expected the item to fail with DeadlineExceeded, got %v", *outcomes)
+	}
+}
+
+func TestBulkReportsPerItemFailures(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"errors":true,"items":[
+			{"index":{"_id":"a","status":201}},
+			{"index":{"_id":"b","status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [followers]"}}},
+			{"index":{"_id":"c","status":200}}
+		]}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+	// MaxDocs of 3 flushes inline on the third profile
+	bulk := NewBulkIndexer(repo, BulkConfig{MaxDocs: 3, MaxBytes: 1 << 20, MaxLinger: time.Hour})
+
+	done, outcomes := collectOutcome()
+	for _, id := range []string{"a", "b", "c"} {
+		bulk.IndexProfile(context.Background(), &models.Influencer{ID: id}, done)
+	}
+
+	if mockTransport.callCount != 1 {
+		t.Fatalf("Expected a full batch to flush on its own, got %d calls", mockTransport.callCount)
+	}
+	if len(*outcomes) != 3 {
+		t.Fatalf("Expected 3 outcomes, got %d", len(*outcomes))
+	}
+	if (*outcomes)[0] != nil || (*outcomes)[2] != nil {
+		t.Errorf("Expected items a and c to succeed, got %v", *outcomes)
+	}
+	if (*outcomes)[1] == nil || !strings.Contains((*outcomes)[1].Error(), "mapper_parsing_exception") {
+		t.Errorf("Expected item b to fail with its reason, got %v", (*outcomes)[1])
+	}
+}
+
+func TestBulkRejectsStaleUpdates(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"errors":true,"items":[
+			{"index":{"_id":"abc","status":409,"error":{"type":"version_conflict_engine_exception","reason":"[abc]: version conflict, current version [20] is higher or equal to the one provided [10]"}}}
+		]}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+	bulk := NewBulkIndexer(repo, BulkConfig{MaxDocs: 10, MaxBytes: 1 << 20, MaxLinger: time.Hour})
+
+	scrapedAt := time.Unix(0, 10)
+	done, outcomes := collectOutcome()
+	bulk.IndexProfile(context.Background(), &models.Influencer{ID: "abc", ScrapedAt: scrapedAt}, done)
+	if err := bulk.Flush(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The scrape time is the external version Elasticsearch compares against
+	if !strings.Contains(mockTransport.lastBody, `"version":10,"version_type":"external"`) {
+		t.Errorf("Expected an externally versioned upsert, got %s", mockTransport.lastBody)
+	}
+	// A stale event is dropped, not retried
+	if len(*outcomes) != 1 || (*outcomes)[0] != nil {
+		t.Errorf("Expected the version conflict to count as done, got %v", *outcomes)
+	}
+}
+
+func TestBulkAppendsMetricsSnapshots(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{{statusCode: 200, body: `{"errors":true,"items":[
+			{"index":{"_id":"a","status":200}},
+			{"create":{"_id":"a-10","status":201}},
+			{"index":{"_id":"b","status":200}},
+			{"create":{"_id":"b-10","status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}
+		]}`}},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index", metricsIndex: "test-metrics"}
+	bulk := NewBulkIndexer(repo, BulkConfig{MaxDocs: 10, MaxBytes: 1 << 20, MaxLinger: time.Hour})
+
+	done, outcomes := collectOutcome()
+	for _, id := range []string{"a", "b"} {
+		bulk.IndexProfile(context.Background(), &models.Influencer{ID: id, Followers: 1200, ScrapedAt: time.Unix(0, 10)}, done)
+	}
+	if err := bulk.Flush(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Snapshot ids come from the scrape time, so a redelivery cannot add a second point
+	if !strings.Contains(mockTransport.lastBody, `{"create":{"_id":"a-10","_index":"test-metrics"}}`) {
+		t.Errorf("Expected a snapshot create action, got %s", mockTransport.lastBody)
+	}
+	if !strings.Contains(mockTransport.lastBody, `"influencer_id":"a","platform":"","followers":1200`) {
+		t.Errorf("Expected the snapshot to carry the metrics, got %s", mockTransport.lastBody)
+	}
+	// Each message's outcome covers both of its writes
+	if len(*outcomes) != 2 || (*outcomes)[0] != nil {
+		t.Fatalf("Expected profile a to succeed, got %v", *outcomes)
+	}
+	if (*outcomes)[1] == nil || !strings.Contains((*outcomes)[1].Error(), "es_rejected_execution_exception") {
+		t.Errorf("Expected profile b to fail with its snapshot, got %v", (*outcomes)[1])
+	}
+}
+
+func TestBulkFlushesOnSizeAndLinger(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 200, body: `{"items":[{"index":{"status":201}}]}`},
+			{statusCode: 200, body: `{"items":[{"index":{"status":201}}]}`},
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	// A single profile is bigger than MaxBytes: flushed right away
+	bySize := NewBulkIndexer(repo, BulkConfig{MaxDocs: 100, MaxBytes: 10, MaxLinger: time.Hour})
+	bySize.IndexProfile(context.Background(), &models.Influencer{Username: "testuser"}, func(error) {})
+	if mockTransport.callCount != 1 {
+		t.Fatalf("Expected a flush once MaxBytes is reached, got %d calls", mockTransport.callCount)
+	}
+
+	// A lone profile is flushed by the linger timer
+	flushed := make(chan error, 1)
+	byTime := NewBulkIndexer(repo, BulkConfig{MaxDocs: 100, MaxBytes: 1 << 20, MaxLinger: 10 * time.Millisecond})
+	byTime.IndexProfile(context.Background(), &models.Influencer{Username: "testuser"}, func(err error) { flushed <- err })
+
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Errorf("Expected linger flush to succeed, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the batch to be flushed after MaxLinger")
+	}
+}
+
+func TestEnsureIndexCreatesMissingIndex(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 404, body: `{}`},                    // GET /_alias/test-index
+			{statusCode: 404, body: ``},                      // HEAD /test-index (no legacy index)
+			{statusCode: 404, body: ``},                      // HEAD /test-index_v1
+			{statusCode: 200, body: `{"acknowledged":true}`}, // PUT /test-index_v1
+			{statusCode: 200, body: `{"acknowledged":true}`}, // PUT /test-index_v1/_aliases/test-index
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	if err := repo.EnsureIndex(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if mockTransport.callCount != 5 {
+		t.Errorf("Expected 5 Elasticsearch calls, got %d", mockTransport.callCount)
+	}
+	if mockTransport.lastPath != "/test-index_v1/_aliases/test-index" {
+		t.Errorf("Expected the read alias to be created on the versioned index, got path %s", mockTransport.lastPath)
+	}
+	if repo.writeTarget() != "test-index_v1" {
+		t.Errorf("Expected writes to go to test-index_v1, got %s", repo.writeTarget())
+	}
+}
+
+func TestEnsureIndexAcceptsMatchingMapping(t *testing.T) {
+	expected, err := expectedMapping(influencerIndex)
+	if err != nil {
+		t.Fatalf("Embedded mapping is invalid: %v", err)
+	}
+	current, _ := json.Marshal(map[string]interface{}{
+		"test-index_v1": map[string]interface{}{"mappings": expected},
+	})
+
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 200, body: `{"test-index_v1": {"aliases": {"test-index": {}}}}`},
+			{statusCode: 200, body: ``},
+			{statusCode: 200, body: string(current)},
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	if err := repo.EnsureIndex(context.Background()); err != nil {
+		t.Fatalf("Expected matching mapping to be accepted, got %v", err)
+	}
+	if mockTransport.callCount != 3 {
+		t.Errorf("Expected no write calls when everything is in place, got %d calls", mockTransport.callCount)
+	}
+}
+
+func TestEnsureIndexRejectsIncompatibleMapping(t *testing.T) {
+	// What Elasticsearch dynamic mapping produces when nobody creates the index
+	dynamicMapping := `{
+		"test-index_v1": {
+			"mappings": {
+				"properties": {
+					"platform": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+					"followers": {"type": "long"}
+				}
+			}
+		}
+	}`
+
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 200, body: `{"test-index_v1": {"aliases": {"test-index": {}}}}`},
+			{statusCode: 200, body: ``},
+			{statusCode: 200, body: dynamicMapping},
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	if err := repo.EnsureIndex(context.Background()); err == nil {
+		t.Fatal("Expected an error for an index created by dynamic mapping")
+	}
+}
+
+func TestEnsureIndexAddsNewFields(t *testing.T) {
+	expected, err := expectedMapping(influencerIndex)
+	if err != nil {
+		t.Fatalf("Embedded mapping is invalid: %v", err)
+	}
+	// An index created before enrichment_status and prices.video existed
+	delete(expected.Properties, "enrichment_status")
+	delete(expected.Properties["prices"].Properties, "video")
+	current, _ := json.Marshal(map[string]interface{}{
+		"test-index_v1": map[string]interface{}{"mappings": expected},
+	})
+
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 200, body: `{"test-index_v1": {"aliases": {"test-index": {}}}}`},
+			{statusCode: 200, body: ``},
+			{statusCode: 200, body: string(current)},
+			{statusCode: 200, body: `{"acknowledged":true}`}, // PUT /test-index_v1/_mapping
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	if err := repo.EnsureIndex(context.Background()); err != nil {
+		t.Fatalf("Expected the new fields to be added, got %v", err)
+	}
+	if mockTransport.callCount != 4 || mockTransport.lastPath != "/test-index_v1/_mapping" {
+		t.Fatalf("Expected a put mapping on the live index, got %d calls ending with %s", mockTransport.callCount, mockTransport.lastPath)
+	}
+	if !strings.Contains(mockTransport.lastBody, `"enrichment_status"`) || !strings.Contains(mockTransport.lastBody, `"video"`) {
+		t.Errorf("Expected the missing fields in the mapping update, got %s", mockTransport.lastBody)
+	}
+}
+
+func TestEnsureIndexRefusesToSplitReadsAndWrites(t *testing.T) {
+	tests := map[string][]MockResponse{
+		"Alias on another version": {
+			{statusCode: 200, body: `{"test-index_v2": {"aliases": {"test-index": {}}}}`},
+		},
+		"Legacy concrete index": {
+			{statusCode: 404, body: `{}`},
+			{statusCode: 200, body: ``},
+		},
+	}
+
+	for name, responses := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockTransport := &MockElasticsearchTransport{responses: responses}
+			esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+			repo := &esRepository{client: esClient, indexName: "test-index"}
+
+			err := repo.EnsureIndex(context.Background())
+			if err == nil || !strings.Contains(err.Error(), "indexer reindex") {
+				t.Fatalf("Expected to be told to reindex, got %v", err)
+			}
+			// Nothing is created for an index search would not read
+			if mockTransport.callCount != len(responses) {
+				t.Errorf("Expected no call after the alias check, got %d calls", mockTransport.callCount)
+			}
+		})
+	}
+}
+
+func TestReindexMigratesLegacyIndex(t *testing.T) {
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 404, body: `{}`},                                                // GET /_alias/test-index
+			{statusCode: 200, body: ``},                                                  // HEAD /test-index (legacy index)
+			{statusCode: 404, body: ``},                                                  // HEAD /test-index_v1
+			{statusCode: 200, body: `{"acknowledged":true}`},                             // PUT /test-index_v1
+			{statusCode: 200, body: `{"created":3,"version_conflicts":1,"failures":[]}`}, // POST /_reindex
+			{statusCode: 200, body: `{"acknowledged":true}`},                             // POST /_aliases
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	result, err := repo.Reindex(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Source != "test-index" || result.Destination != "test-index_v1" {
+		t.Errorf("Unexpected reindex direction %s -> %s", result.Source, result.Destination)
+	}
+	if result.Copied != 3 || result.Skipped != 1 {
+		t.Errorf("Expected 3 copied and 1 skipped, got %d and %d", result.Copied, result.Skipped)
+	}
+
+	// Legacy documents have random _ids, they are copied under their influencer ID
+	var reindex struct {
+		Script struct {
+			Source string `json:"source"`
+		} `json:"script"`
+	}
+	if err := json.Unmarshal([]byte(mockTransport.bodies["/_reindex"]), &reindex); err != nil {
+		t.Fatalf("Failed to decode the reindex request: %v", err)
+	}
+	if !strings.Contains(reindex.Script.Source, "ctx._id = ctx._source.id") {
+		t.Errorf("Expected the legacy documents to be keyed by influencer ID, got script %q", reindex.Script.Source)
+	}
+
+	// The legacy index must be removed in the same atomic call that adds the alias
+	if mockTransport.lastPath != "/_aliases" || !strings.Contains(mockTransport.lastBody, "remove_index") {
+		t.Errorf("Expected an atomic alias swap removing the legacy index, got %s %s", mockTransport.lastPath, mockTransport.lastBody)
+	}
+}
+
+func TestReindexKeepsAliasOnFailures(t *testing.T) {
+	// Search reads from another version, e.g. after rolling a mapping change back
+	mockTransport := &MockElasticsearchTransport{
+		responses: []MockResponse{
+			{statusCode: 200, body: `{"test-index_v2": {"aliases": {"test-index": {}}}}`},
+			{statusCode: 404, body: ``},
+			{statusCode: 200, body: `{"acknowledged":true}`},
+			{statusCode: 200, body: `{"created":1,"failures":[{"id":"abc","cause":{"type":"mapper_parsing_exception"}}]}`},
+		},
+	}
+
+	esClient, _ := elasticsearch.NewClient(elasticsearch.Config{Transport: mockTransport})
+	repo := &esRepository{client: esClient, indexName: "test-index"}
+
+	if _, err := repo.Reindex(context.Background(), true); err == nil {
+		t.Fatal("Expected reindex with failures to return an error")
+	}
+	if mockTransport.callCount != 4 {
+		t.Errorf("Expected no alias swap or delete after failures, got %d calls", mockTransport.callCount)
+	}
+	// Versioned indices already use the influencer ID as _id
+	if strings.Contains(mockTransport.bodies["/_reindex"], "script") {
+		t.Errorf("Expected a plain copy between versioned indices, got %s", mockTransport.bodies["/_reindex"])
+	}
 }
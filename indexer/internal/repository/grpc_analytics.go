@@ -2,10 +2,15 @@ package repository
 
 import (
 	"context"
+	"fmt"
 
 	pb "github.com/hammo/influScope/gen/analytics"
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 type grpcAnalyticsClient struct {
@@ -24,16 +29,142 @@ func NewGRPCAnalyticsClient(target string) (*grpcAnalyticsClient, error) {
 	}, nil
 }
 
-func (g *grpcAnalyticsClient) GetEngagement(ctx context.Context, username string, followers int, platform string) (float64, error) {
-	resp, err := g.client.CalculateEngagement(ctx, &pb.EngagementRequest{
-		Username:  username,
-		Followers: int64(followers),
-		Platform:  platform,
-	})
+func (g *grpcAnalyticsClient) GetEngagement(ctx context.Context, profile *models.Influencer) (domain.Engagement, error) {
+	resp, err := g.client.CalculateEngagement(ctx, engagementRequest(profile))
 	if err != nil {
-		return 0, err
+		return domain.Engagement{}, err
 	}
-	return resp.EngagementRate, nil
+	return toEngagement(resp), nil
+}
+
+// GetEngagementBatch uses the batch RPC, falling back to one call per profile when the
+// analytics service predates it (e.g. halfway through a rolling deploy)
+func (g *grpcAnalyticsClient) GetEngagementBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Engagement, error) {
+	req := &pb.EngagementBatchRequest{Requests: make([]*pb.EngagementRequest, 0, len(profiles))}
+	for _, p := range profiles {
+		req.Requests = append(req.Requests, engagementRequest(p))
+	}
+
+	resp, err := g.client.CalculateEngagementBatch(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		return g.getEngagementOneByOne(ctx, profiles)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Responses) != len(profiles) {
+		return nil, fmt.Errorf("analytics answered %d of %d profiles", len(resp.Responses), len(profiles))
+	}
+
+	scores := make([]domain.Engagement, len(profiles))
+	for i, r := range resp.Responses {
+		scores[i] = toEngagement(r)
+	}
+	return scores, nil
+}
+
+func (g *grpcAnalyticsClient) getEngagementOneByOne(ctx context.Context, profiles []*models.Influencer) ([]domain.Engagement, error) {
+	scores := make([]domain.Engagement, len(profiles))
+	for i, p := range profiles {
+		score, err := g.GetEngagement(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// engagementRequest carries the profile's stats and recent activity to the engagement model
+func engagementRequest(p *models.Influencer) *pb.EngagementRequest {
+	return &pb.EngagementRequest{
+		Username:  p.Username,
+		Followers: int64(p.Followers),
+		Platform:  p.Platform,
+		Category:  p.Category,
+		Likes:     p.Likes,
+		Comments:  p.Comments,
+		Shares:    p.Shares,
+		Views:     p.Views,
+		Posts:     int64(p.Posts),
+	}
+}
+
+// GetAudienceQualityBatch scores the profiles' audiences in one round trip
+func (g *grpcAnalyticsClient) GetAudienceQualityBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.AudienceQuality, error) {
+	req := &pb.AudienceQualityBatchRequest{Requests: make([]*pb.AudienceQualityRequest, 0, len(profiles))}
+	for _, p := range profiles {
+		req.Requests = append(req.Requests, audienceRequest(p))
+	}
+
+	resp, err := g.client.ScoreAudienceQualityBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Responses) != len(profiles) {
+		return nil, fmt.Errorf("analytics answered %d of %d profiles", len(resp.Responses), len(profiles))
+	}
+
+	qualities := make([]domain.AudienceQuality, len(profiles))
+	for i, r := range resp.Responses {
+		qualities[i].Score = int(r.Score)
+		for _, reason := range r.Reasons {
+			qualities[i].Flags = append(qualities[i].Flags, reason.Code)
+		}
+	}
+	return qualities, nil
+}
+
+func audienceRequest(p *models.Influencer) *pb.AudienceQualityRequest {
+	req := &pb.AudienceQualityRequest{
+		Username:         p.Username,
+		Platform:         p.Platform,
+		Category:         p.Category,
+		Followers:        int64(p.Followers),
+		Following:        int64(p.Following),
+		FollowerHistory:  make([]int64, len(p.FollowerHistory)),
+		PostInteractions: p.PostInteractions,
+		EngagementRate:   p.EngagementRate,
+	}
+	for i, f := range p.FollowerHistory {
+		req.FollowerHistory[i] = int64(f)
+	}
+	return req
+}
+
+// GetPriceBatch prices the profiles' sponsored content in one round trip
+func (g *grpcAnalyticsClient) GetPriceBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Prices, error) {
+	req := &pb.PriceBatchRequest{Requests: make([]*pb.PriceRequest, 0, len(profiles))}
+	for _, p := range profiles {
+		req.Requests = append(req.Requests, &pb.PriceRequest{
+			Username:       p.Username,
+			Platform:       p.Platform,
+			Category:       p.Category,
+			Followers:      int64(p.Followers),
+			EngagementRate: p.EngagementRate,
+		})
+	}
+
+	resp, err := g.client.EstimatePriceBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Responses) != len(profiles) {
+		return nil, fmt.Errorf("analytics answered %d of %d profiles", len(resp.Responses), len(profiles))
+	}
+
+	prices := make([]domain.Prices, len(profiles))
+	for i, r := range resp.Responses {
+		prices[i] = domain.Prices{Currency: r.Currency, ByType: make(map[string]models.PriceRange, len(r.Estimates))}
+		for _, e := range r.Estimates {
+			prices[i].ByType[e.ContentType] = models.PriceRange{Low: e.Low, Expected: e.Expected, High: e.High}
+		}
+	}
+	return prices, nil
+}
+
+func toEngagement(resp *pb.EngagementResponse) domain.Engagement {
+	return domain.Engagement{Rate: resp.EngagementRate, ModelVersion: resp.RulesetVersion}
 }
 
 func (g *grpcAnalyticsClient) Close() error {
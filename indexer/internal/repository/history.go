@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// metricsIndexDefinition holds the mapping of the append-only "influencer-metrics" index,
+// which keeps one snapshot of the numbers per ingested event so growth can be charted.
+//
+//go:embed mappings/influencer_metrics.json
+var metricsIndexDefinition []byte
+
+// metricsSnapshot is one point of an influencer's follower and engagement history
+type metricsSnapshot struct {
+	InfluencerID   string    `json:"influencer_id"`
+	Platform       string    `json:"platform"`
+	Followers      int       `json:"followers"`
+	EngagementRate float64   `json:"engagement_rate"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// EnsureMetricsIndex creates the snapshot index if missing, or checks its mapping and adds the
+// fields it lacks. Snapshots are never rewritten, so unlike the profiles they live in a single index.
+func (r *esRepository) EnsureMetricsIndex(ctx context.Context) error {
+	if r.metricsIndex == "" {
+		return nil
+	}
+	return r.prepareIndex(ctx, r.metricsIndex, metricsIndexDefinition)
+}
+
+// snapshotPayload renders the bulk lines appending a snapshot of the profile's metrics.
+// The _id is derived from the scrape time, so a redelivered event does not add a second point.
+func snapshotPayload(index string, profile *models.Influencer) ([]byte, error) {
+	ts := profile.ScrapedAt
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+
+	source, err := json.Marshal(metricsSnapshot{
+		InfluencerID:   profile.ID,
+		Platform:       profile.Platform,
+		Followers:      profile.Followers,
+		EngagementRate: profile.EngagementRate,
+		Timestamp:      ts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	action, err := json.Marshal(map[string]interface{}{
+		"create": map[string]interface{}{
+			"_index": index,
+			"_id":    fmt.Sprintf("%s-%d", profile.ID, ts.UnixNano()),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ndjson(action, source), nil
+}
@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// influencerIndex holds the settings and mappings the "influencers" index is created with.
+// Bump _meta.mapping_version whenever a field type or analyzer changes. New fields do not need
+// a bump, they are added to the live index on startup.
+//
+//go:embed mappings/influencers.json
+var influencerIndex []byte
+
+type indexMapping struct {
+	Meta struct {
+		MappingVersion int `json:"mapping_version"`
+	} `json:"_meta"`
+	Properties map[string]fieldMapping `json:"properties"`
+}
+
+// fieldMapping is a field's type, or the sub-fields of an object field
+type fieldMapping struct {
+	Type       string                  `json:"type"`
+	Properties map[string]fieldMapping `json:"properties"`
+}
+
+// expectedMapping decodes the mapping section of an embedded index definition
+func expectedMapping(definition []byte) (indexMapping, error) {
+	var def struct {
+		Mappings indexMapping `json:"mappings"`
+	}
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return indexMapping{}, fmt.Errorf("invalid embedded index definition: %w", err)
+	}
+	return def.Mappings, nil
+}
+
+// mappingVersion is the _meta.mapping_version of the embedded influencers definition
+func mappingVersion() (int, error) {
+	expected, err := expectedMapping(influencerIndex)
+	if err != nil {
+		return 0, err
+	}
+	return expected.Meta.MappingVersion, nil
+}
+
+// versionedIndex names the concrete index holding a mapping version, e.g. influencers_v2
+func (r *esRepository) versionedIndex(version int) string {
+	return fmt.Sprintf("%s_v%d", r.indexName, version)
+}
+
+// EnsureIndex prepares the versioned index this binary writes to (influencers_vN, created with
+// the explicit mapping if missing, or given the fields it lacks) and refuses to start when an
+// existing one has an incompatible mapping. The read alias is created when nothing exists yet.
+// When search still reads from another index, i.e. the mapping version changed, it refuses to
+// start too: writing to an index nobody reads would hide every update until the reindex.
+func (r *esRepository) EnsureIndex(ctx context.Context) error {
+	version, err := mappingVersion()
+	if err != nil {
+		return err
+	}
+	r.writeIndex = r.versionedIndex(version)
+
+	// 1. Search must read what this binary writes
+	current, _, err := r.readIndex(ctx)
+	if err != nil {
+		return err
+	}
+	if current != "" && current != r.writeIndex {
+		return fmt.Errorf("alias %s reads from %s but mapping version %d lives in %s, run 'indexer reindex' first", r.indexName, current, version, r.writeIndex)
+	}
+
+	// 2. Create the index, or bring in the fields added since it was created
+	if err := r.prepareIndex(ctx, r.writeIndex, influencerIndex); err != nil {
+		return err
+	}
+
+	// 3. First start, nothing to migrate
+	if current == "" {
+		return r.createAlias(ctx)
+	}
+	return nil
+}
+
+// readIndex finds the index search currently reads from, "" when there is none yet. legacy is
+// true when the alias name is taken by a concrete index from before versioned indices existed.
+func (r *esRepository) readIndex(ctx context.Context) (string, bool, error) {
+	targets, err := r.aliasTargets(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if len(targets) > 1 {
+		return "", false, fmt.Errorf("alias %s points to several indices %v, fix it manually", r.indexName, targets)
+	}
+	if len(targets) == 1 {
+		return targets[0], false, nil
+	}
+
+	legacy, err := r.indexExists(ctx, r.indexName)
+	if err != nil {
+		return "", false, err
+	}
+	if legacy {
+		return r.indexName, true, nil
+	}
+	return "", false, nil
+}
+
+// createAlias points the read alias at the write index
+func (r *esRepository) createAlias(ctx context.Context) error {
+	res, err := r.client.Indices.PutAlias(
+		[]string{r.writeIndex},
+		r.indexName,
+		r.client.Indices.PutAlias.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("creating alias %s failed: %s", r.indexName, res.String())
+	}
+	return nil
+}
+
+// aliasTargets lists the concrete indices behind the read alias (empty when there is no alias)
+func (r *esRepository) aliasTargets(ctx context.Context) ([]string, error) {
+	res, err := r.client.Indices.GetAlias(
+		r.client.Indices.GetAlias.WithName(r.indexName),
+		r.client.Indices.GetAlias.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("reading alias %s failed: %s", r.indexName, res.String())
+	}
+
+	var indices map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("decoding alias %s failed: %w", r.indexName, err)
+	}
+
+	targets := make([]string, 0, len(indices))
+	for name := range indices {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+func (r *esRepository) indexExists(ctx context.Context, index string) (bool, error) {
+	res, err := r.client.Indices.Exists([]string{index}, r.client.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case 200:
+		return true, nil
+	case 404:
+		return false, nil
+	default:
+		return false, fmt.Errorf("checking index %s failed: %s", index, res.Status())
+	}
+}
+
+// prepareIndex creates index from one of the embedded definitions, or brings an existing one
+// up to date with it
+func (r *esRepository) prepareIndex(ctx context.Context, index string, definition []byte) error {
+	exists, err := r.indexExists(ctx, index)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return r.syncMapping(ctx, index, definition)
+	}
+	return r.createIndex(ctx, index, definition)
+}
+
+// createIndex creates index from one of the embedded definitions
+func (r *esRepository) createIndex(ctx context.Context, index string, definition []byte) error {
+	res, err := r.client.Indices.Create(
+		index,
+		r.client.Indices.Create.WithBody(bytes.NewReader(definition)),
+		r.client.Indices.Create.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		body := res.String()
+		// Another indexer replica won the race, make sure it created the same thing
+		if strings.Contains(body, "resource_already_exists_exception") {
+			return r.syncMapping(ctx, index, definition)
+		}
+		return fmt.Errorf("creating index %s failed: %s", index, body)
+	}
+	return nil
+}
+
+// syncMapping checks the mapping of index against definition and adds the fields it lacks.
+// Elasticsearch merges new fields into a live index, a changed type needs a new mapping version.
+func (r *esRepository) syncMapping(ctx context.Context, index string, definition []byte) error {
+	missing, err := r.checkMapping(ctx, index, definition)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+
+	log.Printf("Adding %s to the mapping of %s", strings.Join(missing, ", "), index)
+	return r.putMapping(ctx, index, definition)
+}
+
+// checkMapping returns the fields of definition that index does not have yet, or an error
+// when a field it has is incompatible
+func (r *esRepository) checkMapping(ctx context.Context, index string, definition []byte) ([]string, error) {
+	expected, err := expectedMapping(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Indices.GetMapping(
+		r.client.Indices.GetMapping.WithIndex(index),
+		r.client.Indices.GetMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("reading mapping of %s failed: %s", index, res.String())
+	}
+
+	var indices map[string]struct {
+		Mappings indexMapping `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("decoding mapping of %s failed: %w", index, err)
+	}
+
+	var missing []string
+	for name, index := range indices {
+		fields, err := compareMappings(expected, index.Mappings)
+		if err != nil {
+			return nil, fmt.Errorf("index %s has an incompatible mapping: %w", name, err)
+		}
+		missing = append(missing, fields...)
+	}
+	return missing, nil
+}
+
+// putMapping sends the fields of definition to index. Fields it already has are left as they
+// are, so the whole definition can be sent rather than just the missing fields.
+func (r *esRepository) putMapping(ctx context.Context, index string, definition []byte) error {
+	var def struct {
+		Mappings struct {
+			Properties json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal(definition, &def); err != nil {
+		return fmt.Errorf("invalid embedded index definition: %w", err)
+	}
+	body, err := json.Marshal(map[string]json.RawMessage{"properties": def.Mappings.Properties})
+	if err != nil {
+		return err
+	}
+
+	res, err := r.client.Indices.PutMapping(
+		bytes.NewReader(body),
+		r.client.Indices.PutMapping.WithIndex(index),
+		r.client.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("updating mapping of %s failed: %s", index, res.String())
+	}
+	return nil
+}
+
+// compareMappings lists the expected fields the actual mapping lacks, by path, and reports
+// the first incompatible difference
+func compareMappings(expected, actual indexMapping) ([]string, error) {
+	if actual.Meta.MappingVersion != expected.Meta.MappingVersion {
+		return nil, fmt.Errorf("mapping version is %d, expected %d", actual.Meta.MappingVersion, expected.Meta.MappingVersion)
+	}
+	return compareFields("", expected.Properties, actual.Properties)
+}
+
+func compareFields(prefix string, expected, actual map[string]fieldMapping) ([]string, error) {
+	var missing []string
+	for field, want := range expected {
+		path := prefix + field
+		got, ok := actual[field]
+		if !ok {
+			missing = append(missing, path)
+			continue
+		}
+		if got.Type != want.Type {
+			return nil, fmt.Errorf("field %q is %q, expected %q", path, got.Type, want.Type)
+		}
+		fields, err := compareFields(path+".", want.Properties, got.Properties)
+		if err != nil {
+			return nil, err
+		}
+		missing = append(missing, fields...)
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
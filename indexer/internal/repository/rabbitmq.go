@@ -3,41 +3,172 @@ package repository
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"time"
 
 	"github.com/hammo/influScope/indexer/internal/domain"
 	"github.com/upfluence/amqp"
 	"github.com/upfluence/amqp/amqputil"
 )
 
+const (
+	// retryCountHeader counts how many times a message has already been retried
+	retryCountHeader = "x-retry-count"
+	// lastErrorHeader keeps the reason of the latest failure, for DLQ inspection
+	lastErrorHeader = "x-last-error"
+)
+
+// RetryPolicy bounds how often a failing message is retried before it is dead-lettered.
+// Attempt n waits BaseDelay * 2^(n-1) in a TTL queue before going back to the work queue.
+type RetryPolicy struct {
+	MaxAttempts int // Total deliveries, including the first one
+	BaseDelay   time.Duration
+}
+
+// delay returns how long the given retry (starting at 1) waits
+func (p RetryPolicy) delay(retry int) time.Duration {
+	return p.BaseDelay << (retry - 1)
+}
+
+// topology names everything declared around the work queue:
+// <exchange>.retry routes "retry.<n>" to <queue>.retry.<n>, whose TTL dead-letters back to <queue>,
+// and <exchange>.dlx fans out to <exchange>.dlq for messages that ran out of attempts.
+type topology struct {
+	exchange      string
+	queue         string
+	retryExchange string
+	dlxExchange   string
+	dlq           string
+}
+
+func newTopology(exchange, queue string) topology {
+	return topology{
+		exchange:      exchange,
+		queue:         queue,
+		retryExchange: exchange + ".retry",
+		dlxExchange:   exchange + ".dlx",
+		dlq:           exchange + ".dlq",
+	}
+}
+
+func (t topology) retryQueue(retry int) string { return fmt.Sprintf("%s.retry.%d", t.queue, retry) }
+func (t topology) retryKey(retry int) string   { return fmt.Sprintf("retry.%d", retry) }
+
+// declare sets up the work queue, the delayed retry queues and the dead-letter queue
+func (t topology) declare(ctx context.Context, broker amqp.Broker, policy RetryPolicy) error {
+	if err := broker.DeclareQueue(ctx, t.queue, amqp.DeclareQueueOptions{Durable: true}); err != nil {
+		return fmt.Errorf("declare queue failed: %w", err)
+	}
+	if err := broker.BindQueue(ctx, t.queue, "#", t.exchange, amqp.BindQueueOptions{}); err != nil {
+		return fmt.Errorf("bind queue failed: %w", err)
+	}
+
+	if err := broker.DeclareExchange(ctx, t.retryExchange, amqp.Direct, amqp.DeclareExchangeOptions{Durable: true}); err != nil {
+		return fmt.Errorf("declare retry exchange failed: %w", err)
+	}
+	for retry := 1; retry < policy.MaxAttempts; retry++ {
+		queue := t.retryQueue(retry)
+		err := broker.DeclareQueue(ctx, queue, amqp.DeclareQueueOptions{
+			Durable: true,
+			Args: map[string]any{
+				"x-message-ttl": policy.delay(retry).Milliseconds(),
+				// The default exchange routes by queue name, straight back to the work queue
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": t.queue,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("declare retry queue %s failed: %w", queue, err)
+		}
+		if err := broker.BindQueue(ctx, queue, t.retryKey(retry), t.retryExchange, amqp.BindQueueOptions{}); err != nil {
+			return fmt.Errorf("bind retry queue %s failed: %w", queue, err)
+		}
+	}
+
+	if err := broker.DeclareExchange(ctx, t.dlxExchange, amqp.Fanout, amqp.DeclareExchangeOptions{Durable: true}); err != nil {
+		return fmt.Errorf("declare dead-letter exchange failed: %w", err)
+	}
+	if err := broker.DeclareQueue(ctx, t.dlq, amqp.DeclareQueueOptions{Durable: true}); err != nil {
+		return fmt.Errorf("declare dead-letter queue failed: %w", err)
+	}
+	if err := broker.BindQueue(ctx, t.dlq, "", t.dlxExchange, amqp.BindQueueOptions{}); err != nil {
+		return fmt.Errorf("bind dead-letter queue failed: %w", err)
+	}
+	return nil
+}
+
 type upfluenceConsumer struct {
 	broker   amqp.Broker
 	consumer amqp.Consumer
 	ctx      context.Context
+	topology topology
+	policy   RetryPolicy
 }
 
 type upfluenceMessage struct {
 	ctx      context.Context
-	consumer amqp.Consumer
+	consumer *upfluenceConsumer
 	delivery *amqp.Delivery
 }
 
 func (m *upfluenceMessage) Body() []byte { return m.delivery.Message.Body }
 func (m *upfluenceMessage) Ack() error {
-	return m.consumer.Ack(m.ctx, m.delivery.DeliveryTag, amqp.AckOptions{})
+	return m.consumer.consumer.Ack(m.ctx, m.delivery.DeliveryTag, amqp.AckOptions{})
 }
 
-func NewRabbitMQConsumer(ctx context.Context, exchange, queue string) (domain.MessageConsumer, error) {
+// Attempts is how many times this message has been delivered before, according to its headers
+func (m *upfluenceMessage) Attempts() int {
+	return headerInt(m.delivery.Message.Headers, retryCountHeader)
+}
+
+func (m *upfluenceMessage) Requeue() error {
+	return m.consumer.consumer.Nack(m.ctx, m.delivery.DeliveryTag, amqp.NackOptions{Requeue: true})
+}
+
+// Nack schedules a delayed retry, or dead-letters the message once MaxAttempts is reached.
+// The copy is published before the original is acked; if publishing fails the original is
+// requeued instead so nothing is lost.
+func (m *upfluenceMessage) Nack(reason error) error {
+	retry := m.Attempts() + 1
+	msg := m.delivery.Message
+	msg.Headers = copyHeaders(msg.Headers)
+	msg.Headers[lastErrorHeader] = reason.Error()
+
+	exchange, key := m.consumer.topology.dlxExchange, ""
+	if retry < m.consumer.policy.MaxAttempts {
+		exchange, key = m.consumer.topology.retryExchange, m.consumer.topology.retryKey(retry)
+		msg.Headers[retryCountHeader] = int64(retry)
+	} else {
+		log.Printf("Message exhausted %d attempts, moving it to %s: %v", m.consumer.policy.MaxAttempts, m.consumer.topology.dlq, reason)
+	}
+
+	if err := m.consumer.broker.Publish(m.ctx, exchange, key, msg, amqp.PublishOptions{}); err != nil {
+		log.Printf("Failed to schedule retry, requeueing instead: %v", err)
+		return m.Requeue()
+	}
+	return m.Ack()
+}
+
+// NewRabbitMQConsumer starts consuming the work queue. prefetch caps how many unacked
+// messages the broker pushes to this instance (0 means unlimited); since messages stay
+// unacked until their bulk request is flushed, it should cover at least one full batch.
+func NewRabbitMQConsumer(ctx context.Context, exchange, queue string, prefetch int, policy RetryPolicy) (domain.MessageConsumer, error) {
 	broker := amqputil.Open()
+	t := newTopology(exchange, queue)
 
-	if err := broker.DeclareQueue(ctx, queue, amqp.DeclareQueueOptions{Durable: true}); err != nil {
-		return nil, fmt.Errorf("declare queue failed: %w", err)
+	if err := t.declare(ctx, broker, policy); err != nil {
+		return nil, err
 	}
-	if err := broker.BindQueue(ctx, queue, "#", exchange, amqp.BindQueueOptions{}); err != nil {
-		return nil, fmt.Errorf("bind queue failed: %w", err)
+
+	// QoS is per channel: calls are sequential here, so the pool hands the same idle
+	// channel to Consume and the limit applies to the consumer started on it
+	if err := broker.Qos(ctx, amqp.QosOptions{PrefetchCount: prefetch}); err != nil {
+		return nil, fmt.Errorf("qos failed: %w", err)
 	}
 
 	consumer, err := broker.Consume(ctx, queue, amqp.ConsumeOptions{
-		Consumer: "indexer-worker-1",
+		Consumer: consumerTag(),
 		AutoACK:  false,
 	})
 	if err != nil {
@@ -48,6 +179,8 @@ func NewRabbitMQConsumer(ctx context.Context, exchange, queue string) (domain.Me
 		broker:   broker,
 		consumer: consumer,
 		ctx:      ctx,
+		topology: t,
+		policy:   policy,
 	}, nil
 }
 
@@ -56,9 +189,11 @@ func (c *upfluenceConsumer) Next(ctx context.Context) (domain.Message, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Acks use the connection context rather than ctx: a message received just before
+	// shutdown is still acked or retried after the caller stopped consuming
 	return &upfluenceMessage{
-		ctx:      ctx,
-		consumer: c.consumer,
+		ctx:      c.ctx,
+		consumer: c,
 		delivery: delivery,
 	}, nil
 }
@@ -67,3 +202,35 @@ func (c *upfluenceConsumer) Close() error {
 	c.consumer.Close()
 	return c.broker.Close()
 }
+
+// consumerTag names this instance in the RabbitMQ management UI, e.g. "indexer-<pod name>"
+func consumerTag() string {
+	host, err := os.Hostname()
+	if err != nil {
+		// Let the broker generate a unique tag
+		return ""
+	}
+	return fmt.Sprintf("indexer-%s-%d", host, os.Getpid())
+}
+
+// headerInt reads an integer header whatever numeric type the broker decoded it as
+func headerInt(headers map[string]any, key string) int {
+	switch v := headers[key].(type) {
+	case int:
+		return v
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+func copyHeaders(headers map[string]any) map[string]any {
+	out := make(map[string]any, len(headers)+2)
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/upfluence/amqp"
+)
+
+// --- MOCKS ---
+
+type publishedMessage struct {
+	exchange string
+	key      string
+	msg      amqp.Message
+}
+
+type mockBroker struct {
+	published  []publishedMessage
+	publishErr error
+	queues     map[string]amqp.DeclareQueueOptions
+	consumer   *mockAMQPConsumer
+}
+
+func (b *mockBroker) Publish(ctx context.Context, exchange, key string, msg amqp.Message, _ amqp.PublishOptions) error {
+	if b.publishErr != nil {
+		return b.publishErr
+	}
+	b.published = append(b.published, publishedMessage{exchange, key, msg})
+	return nil
+}
+
+func (b *mockBroker) Consume(context.Context, string, amqp.ConsumeOptions) (amqp.Consumer, error) {
+	return b.consumer, nil
+}
+
+func (b *mockBroker) Qos(context.Context, amqp.QosOptions) error { return nil }
+
+func (b *mockBroker) DeclareQueue(_ context.Context, name string, opts amqp.DeclareQueueOptions) error {
+	if b.queues == nil {
+		b.queues = map[string]amqp.DeclareQueueOptions{}
+	}
+	b.queues[name] = opts
+	return nil
+}
+
+func (b *mockBroker) DeclareExchange(context.Context, string, amqp.ExchangeKind, amqp.DeclareExchangeOptions) error {
+	return nil
+}
+
+func (b *mockBroker) BindQueue(context.Context, string, string, string, amqp.BindQueueOptions) error {
+	return nil
+}
+
+func (b *mockBroker) Close() error { return nil }
+
+type mockAMQPConsumer struct {
+	deliveries []*amqp.Delivery
+	acked      []uint64
+	nacked     []uint64
+	requeued   bool
+}
+
+func (c *mockAMQPConsumer) Next(ctx context.Context) (*amqp.Delivery, error) {
+	if len(c.deliveries) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	d := c.deliveries[0]
+	c.deliveries = c.deliveries[1:]
+	return d, nil
+}
+
+func (c *mockAMQPConsumer) Ack(_ context.Context, tag uint64, _ amqp.AckOptions) error {
+	c.acked = append(c.acked, tag)
+	return nil
+}
+
+func (c *mockAMQPConsumer) Nack(_ context.Context, tag uint64, opts amqp.NackOptions) error {
+	c.nacked = append(c.nacked, tag)
+	c.requeued = opts.Requeue
+	return nil
+}
+
+func (c *mockAMQPConsumer) IsOpen() bool { return true }
+func (c *mockAMQPConsumer) Close() error { return nil }
+
+func newTestMessage(broker *mockBroker, headers map[string]any) (*upfluenceMessage, *mockAMQPConsumer) {
+	consumer := &mockAMQPConsumer{}
+	c := &upfluenceConsumer{
+		broker:   broker,
+		consumer: consumer,
+		topology: newTopology("influencer-events", "indexer-queue"),
+		policy:   RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second},
+	}
+	return &upfluenceMessage{
+		ctx:      context.Background(),
+		consumer: c,
+		delivery: &amqp.Delivery{
+			Message:     amqp.Message{Body: []byte(`{"id":"abc"}`), Headers: headers},
+			DeliveryTag: 7,
+		},
+	}, consumer
+}
+
+// --- TESTS ---
+
+func TestTopologyDeclaresRetryQueuesWithBackoff(t *testing.T) {
+	broker := &mockBroker{}
+	policy := RetryPolicy{MaxAttempts: 4, BaseDelay: 5 * time.Second}
+
+	if err := newTopology("influencer-events", "indexer-queue").declare(context.Background(), broker, policy); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// One delayed queue per retry, doubling each time
+	expected := map[string]int64{
+		"indexer-queue.retry.1": 5000,
+		"indexer-queue.retry.2": 10000,
+		"indexer-queue.retry.3": 20000,
+	}
+	for name, ttl := range expected {
+		opts, ok := broker.queues[name]
+		if !ok {
+			t.Fatalf("Expected queue %s to be declared", name)
+		}
+		if opts.Args["x-message-ttl"] != ttl {
+			t.Errorf("Expected %s TTL %d, got %v", name, ttl, opts.Args["x-message-ttl"])
+		}
+		if opts.Args["x-dead-letter-routing-key"] != "indexer-queue" {
+			t.Errorf("Expected %s to dead-letter back to indexer-queue, got %v", name, opts.Args["x-dead-letter-routing-key"])
+		}
+	}
+	if _, ok := broker.queues["indexer-queue.retry.4"]; ok {
+		t.Error("Expected no retry queue past MaxAttempts")
+	}
+	if _, ok := broker.queues["influencer-events.dlq"]; !ok {
+		t.Error("Expected the DLQ to be declared")
+	}
+}
+
+func TestNackSchedulesRetry(t *testing.T) {
+	broker := &mockBroker{}
+	msg, consumer := newTestMessage(broker, nil)
+
+	if err := msg.Nack(errors.New("es_rejected_execution_exception")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(broker.published) != 1 {
+		t.Fatalf("Expected 1 republished message, got %d", len(broker.published))
+	}
+	p := broker.published[0]
+	if p.exchange != "influencer-events.retry" || p.key != "retry.1" {
+		t.Errorf("Expected retry.1 on the retry exchange, got %s/%s", p.exchange, p.key)
+	}
+	if p.msg.Headers[retryCountHeader] != int64(1) {
+		t.Errorf("Expected retry count 1, got %v", p.msg.Headers[retryCountHeader])
+	}
+	if p.msg.Headers[lastErrorHeader] != "es_rejected_execution_exception" {
+		t.Errorf("Expected the failure reason in the headers, got %v", p.msg.Headers[lastErrorHeader])
+	}
+	if len(consumer.acked) != 1 {
+		t.Errorf("Expected the original delivery to be ACKed once the copy is queued")
+	}
+}
+
+func TestNackDeadLettersAfterMaxAttempts(t *testing.T) {
+	broker := &mockBroker{}
+	// Already retried twice, the broker decodes integer headers as int32
+	msg, consumer := newTestMessage(broker, map[string]any{retryCountHeader: int32(2)})
+
+	if err := msg.Nack(errors.New("mapper_parsing_exception")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(broker.published) != 1 || broker.published[0].exchange != "influencer-events.dlx" {
+		t.Fatalf("Expected the message on the dead-letter exchange, got %+v", broker.published)
+	}
+	if len(consumer.acked) != 1 {
+		t.Errorf("Expected the original delivery to be ACKed")
+	}
+}
+
+func TestNackRequeuesWhenRetryCannotBePublished(t *testing.T) {
+	broker := &mockBroker{publishErr: errors.New("channel closed")}
+	msg, consumer := newTestMessage(broker, nil)
+
+	if err := msg.Nack(errors.New("timeout")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Nothing may be lost: the original goes straight back to the work queue
+	if len(consumer.acked) != 0 {
+		t.Error("Expected the original delivery not to be ACKed")
+	}
+	if len(consumer.nacked) != 1 || !consumer.requeued {
+		t.Errorf("Expected the original delivery to be requeued, got %v", consumer.nacked)
+	}
+}
+
+func TestReplayResetsRetryBudget(t *testing.T) {
+	consumer := &mockAMQPConsumer{deliveries: []*amqp.Delivery{
+		{Message: amqp.Message{Body: []byte(`{"id":"a"}`), Headers: map[string]any{retryCountHeader: int32(4), lastErrorHeader: "boom"}}, DeliveryTag: 1},
+		{Message: amqp.Message{Body: []byte(`{"id":"b"}`), Headers: map[string]any{retryCountHeader: int32(4)}}, DeliveryTag: 2},
+	}}
+	broker := &mockBroker{consumer: consumer}
+	dlq := &deadLetterQueue{broker: broker, topology: newTopology("influencer-events", "indexer-queue")}
+
+	replayed, err := dlq.Replay(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if replayed != 2 || len(consumer.acked) != 2 {
+		t.Fatalf("Expected 2 replayed and ACKed messages, got %d and %d", replayed, len(consumer.acked))
+	}
+	for _, p := range broker.published {
+		if p.exchange != "" || p.key != "indexer-queue" {
+			t.Errorf("Expected replay straight to indexer-queue, got %s/%s", p.exchange, p.key)
+		}
+		if _, ok := p.msg.Headers[retryCountHeader]; ok {
+			t.Error("Expected the retry count to be cleared")
+		}
+	}
+}
+
+func TestInspectLeavesMessagesInQueue(t *testing.T) {
+	consumer := &mockAMQPConsumer{deliveries: []*amqp.Delivery{
+		{Message: amqp.Message{Body: []byte(`{"id":"a"}`), Headers: map[string]any{retryCountHeader: int32(4), lastErrorHeader: "boom"}}, DeliveryTag: 1},
+		{Message: amqp.Message{Body: []byte(`{"id":"b"}`)}, DeliveryTag: 2},
+	}}
+	broker := &mockBroker{consumer: consumer}
+	dlq := &deadLetterQueue{broker: broker, topology: newTopology("influencer-events", "indexer-queue")}
+
+	letters, err := dlq.Inspect(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(letters) != 1 || letters[0].Attempts != 5 || letters[0].LastError != "boom" {
+		t.Fatalf("Unexpected dead letters: %+v", letters)
+	}
+	if len(consumer.acked) != 0 || len(consumer.nacked) != 1 || !consumer.requeued {
+		t.Errorf("Expected the inspected message to be requeued, got %d acks and %d nacks", len(consumer.acked), len(consumer.nacked))
+	}
+}
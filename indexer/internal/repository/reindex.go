@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// ReindexResult summarises a reindex run
+type ReindexResult struct {
+	Source      string
+	Destination string
+	Copied      int
+	Skipped     int // Documents the destination already holds in a newer version, e.g. from an earlier run
+}
+
+// Reindex copies every document from the index currently behind the read alias into the
+// versioned index of this binary's mapping, then atomically moves the alias over.
+// A legacy concrete index named like the alias is migrated the same way and removed in the swap.
+// With deleteOld the previous versioned index is dropped once the alias has moved.
+func (r *esRepository) Reindex(ctx context.Context, deleteOld bool) (*ReindexResult, error) {
+	version, err := mappingVersion()
+	if err != nil {
+		return nil, err
+	}
+	dest := r.versionedIndex(version)
+
+	source, legacy, err := r.reindexSource(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if source == dest {
+		return nil, fmt.Errorf("alias %s already points to %s, nothing to reindex", r.indexName, dest)
+	}
+
+	if err := r.prepareIndex(ctx, dest, influencerIndex); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Reindexing %s into %s...", source, dest)
+	result, err := r.copyDocuments(ctx, source, dest, legacy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.swapAlias(ctx, source, dest, legacy); err != nil {
+		return nil, err
+	}
+	log.Printf("Alias %s now points to %s", r.indexName, dest)
+
+	if deleteOld && !legacy {
+		if err := r.deleteIndex(ctx, source); err != nil {
+			return result, err
+		}
+		log.Printf("Deleted %s", source)
+	}
+	return result, nil
+}
+
+// reindexSource finds the index search currently reads from
+func (r *esRepository) reindexSource(ctx context.Context) (string, bool, error) {
+	source, legacy, err := r.readIndex(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if source == "" {
+		return "", false, fmt.Errorf("neither alias nor index %s exists", r.indexName)
+	}
+	return source, legacy, nil
+}
+
+// legacyIDScript gives documents of the legacy index their influencer ID as _id. That index was
+// written without one, so its documents have random _ids that lookups by ID and live upserts
+// would never hit.
+const legacyIDScript = "if (ctx._source.id != null && ctx._source.id != '') { ctx._id = ctx._source.id }"
+
+// copyDocuments runs a blocking _reindex. External versioning carries the scraped_at versions
+// over and keeps documents the destination already holds in a newer version, e.g. after an
+// interrupted run. When several legacy documents share an ID, the first one copied is kept.
+func (r *esRepository) copyDocuments(ctx context.Context, source, dest string, legacy bool) (*ReindexResult, error) {
+	request := map[string]interface{}{
+		"conflicts": "proceed",
+		"source":    map[string]interface{}{"index": source},
+		"dest":      map[string]interface{}{"index": dest, "version_type": "external"},
+	}
+	if legacy {
+		request["script"] = map[string]interface{}{"lang": "painless", "source": legacyIDScript}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Reindex(
+		bytes.NewReader(body),
+		r.client.Reindex.WithWaitForCompletion(true),
+		r.client.Reindex.WithRefresh(true),
+		r.client.Reindex.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("reindex %s -> %s failed: %s", source, dest, res.String())
+	}
+
+	var summary struct {
+		Created          int               `json:"created"`
+		VersionConflicts int               `json:"version_conflicts"`
+		Failures         []json.RawMessage `json:"failures"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&summary); err != nil {
+		return nil, fmt.Errorf("decoding reindex response failed: %w", err)
+	}
+	if len(summary.Failures) > 0 {
+		// Leave the alias alone, search keeps working on the old index
+		return nil, fmt.Errorf("reindex %s -> %s had %d failures, first: %s", source, dest, len(summary.Failures), summary.Failures[0])
+	}
+
+	return &ReindexResult{
+		Source:      source,
+		Destination: dest,
+		Copied:      summary.Created,
+		Skipped:     summary.VersionConflicts,
+	}, nil
+}
+
+// swapAlias moves the read alias in a single _aliases call so search never sees a gap
+func (r *esRepository) swapAlias(ctx context.Context, source, dest string, legacy bool) error {
+	remove := map[string]interface{}{
+		"remove": map[string]interface{}{"index": source, "alias": r.indexName},
+	}
+	if legacy {
+		// The alias name is held by a concrete index, which has to go in the same call
+		remove = map[string]interface{}{
+			"remove_index": map[string]interface{}{"index": source},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"actions": []interface{}{
+			remove,
+			map[string]interface{}{
+				"add": map[string]interface{}{"index": dest, "alias": r.indexName},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := r.client.Indices.UpdateAliases(
+		bytes.NewReader(body),
+		r.client.Indices.UpdateAliases.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("swapping alias %s to %s failed: %s", r.indexName, dest, res.String())
+	}
+	return nil
+}
+
+func (r *esRepository) deleteIndex(ctx context.Context, index string) error {
+	res, err := r.client.Indices.Delete([]string{index}, r.client.Indices.Delete.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("deleting index %s failed: %s", index, res.String())
+	}
+	return nil
+}
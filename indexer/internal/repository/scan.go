@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// ScanProfiles pages through the profiles behind the read alias with search_after on the id
+// field. Unlike a scroll context the position is just the last ID, so a job can stop and pick
+// up again later. Documents without an ID cannot be addressed and are skipped.
+func (r *esRepository) ScanProfiles(ctx context.Context, afterID string, size int) ([]domain.StoredProfile, error) {
+	query := map[string]interface{}{
+		"size":                size,
+		"query":               map[string]interface{}{"exists": map[string]interface{}{"field": "id"}},
+		"sort":                []interface{}{map[string]interface{}{"id": "asc"}},
+		"seq_no_primary_term": true,
+	}
+	if afterID != "" {
+		query["search_after"] = []interface{}{afterID}
+	}
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Search(
+		r.client.Search.WithContext(ctx),
+		r.client.Search.WithIndex(r.indexName),
+		r.client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("scanning %s failed: %s", r.indexName, res.String())
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				SeqNo       int64              `json:"_seq_no"`
+				PrimaryTerm int64              `json:"_primary_term"`
+				Source      *models.Influencer `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding scan response failed: %w", err)
+	}
+
+	profiles := make([]domain.StoredProfile, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		if hit.Source == nil {
+			continue
+		}
+		profiles = append(profiles, domain.StoredProfile{Profile: hit.Source, SeqNo: hit.SeqNo, PrimaryTerm: hit.PrimaryTerm})
+	}
+	return profiles, nil
+}
+
+// UpdateEnrichment sends one _bulk request of partial updates carrying only the analytics
+// fields. The scraper's fields and the scraped_at version are left as they are, and
+// if_seq_no makes a document the live indexer rewrote meanwhile fail with a conflict
+// instead of being overwritten with older data.
+func (r *esRepository) UpdateEnrichment(ctx context.Context, profiles []domain.StoredProfile) (int, error) {
+	if len(profiles) == 0 {
+		return 0, nil
+	}
+
+	var buf bytes.Buffer
+	for _, sp := range profiles {
+		action, err := json.Marshal(map[string]interface{}{
+			"update": map[string]interface{}{
+				"_index":          r.indexName,
+				"_id":             sp.Profile.ID,
+				"if_seq_no":       sp.SeqNo,
+				"if_primary_term": sp.PrimaryTerm,
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+		source, err := json.Marshal(map[string]interface{}{"doc": enrichmentFields(sp.Profile)})
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(ndjson(action, source))
+	}
+
+	res, err := r.client.Bulk(bytes.NewReader(buf.Bytes()), r.client.Bulk.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return 0, fmt.Errorf("bulk update failed: %s", res.String())
+	}
+
+	var body struct {
+		Items []map[string]struct {
+			Status int `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("decoding bulk response failed: %w", err)
+	}
+
+	conflicts := 0
+	for _, item := range body.Items {
+		for _, result := range item {
+			switch {
+			case result.Status == http.StatusConflict:
+				conflicts++
+			case result.Error != nil:
+				return conflicts, fmt.Errorf("update failed (%d): %s: %s", result.Status, result.Error.Type, result.Error.Reason)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// enrichmentFields are the fields of a profile computed by analytics. Every one is written,
+// even when empty, so a partial update also clears values that no longer apply.
+func enrichmentFields(p *models.Influencer) map[string]interface{} {
+	fields := map[string]interface{}{
+		"engagement_rate":         p.EngagementRate,
+		"analytics_model_version": p.AnalyticsModelVersion,
+		"audience_quality":        p.AudienceQuality,
+		"audience_flags":          p.AudienceFlags,
+		"prices":                  p.Prices,
+		"price_currency":          p.PriceCurrency,
+		"degraded":                p.Degraded,
+		"enrichment_status":       p.EnrichmentStatus,
+		"enriched_at":             nil,
+	}
+	if !p.EnrichedAt.IsZero() {
+		fields["enriched_at"] = p.EnrichedAt
+	}
+	return fields
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// enrichFailures records which analytics calls failed for a batch
+type enrichFailures struct {
+	engagement, audience, prices bool
+	err                          error // First failure, for reporting
+}
+
+func (f enrichFailures) any() bool {
+	return f.engagement || f.audience || f.prices
+}
+
+func (f enrichFailures) status() string {
+	switch {
+	case f.engagement:
+		return models.EnrichmentFailed
+	case f.audience || f.prices:
+		return models.EnrichmentPartial
+	default:
+		return models.EnrichmentComplete
+	}
+}
+
+// enrich stores the analytics results on the profiles, each call bounded by timeout. Audience
+// quality and prices are computed from the fresh engagement rates, so they are only requested
+// once the rates are known. Fields whose call failed are left untouched.
+func enrich(ctx context.Context, analytics domain.AnalyticsClient, profiles []*models.Influencer, now time.Time, timeout time.Duration) enrichFailures {
+	grpcCtx, cancel := context.WithTimeout(ctx, timeout)
+	scores, err := analytics.GetEngagementBatch(grpcCtx, profiles)
+	cancel()
+
+	if err != nil {
+		log.Printf("Analytics Service failed: %v", err)
+		return enrichFailures{engagement: true, audience: true, prices: true, err: err}
+	}
+	for i, p := range profiles {
+		p.EngagementRate = scores[i].Rate
+		p.AnalyticsModelVersion = scores[i].ModelVersion
+		p.EnrichedAt = now
+	}
+
+	failed := enrichFailures{}
+	if err := scoreAudience(ctx, analytics, profiles, timeout); err != nil {
+		failed.audience, failed.err = true, err
+	}
+	if err := estimatePrices(ctx, analytics, profiles, timeout); err != nil {
+		failed.prices = true
+		if failed.err == nil {
+			failed.err = err
+		}
+	}
+	return failed
+}
+
+// scoreAudience stores the audience quality on the profiles
+func scoreAudience(ctx context.Context, analytics domain.AnalyticsClient, profiles []*models.Influencer, timeout time.Duration) error {
+	grpcCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	qualities, err := analytics.GetAudienceQualityBatch(grpcCtx, profiles)
+	if err != nil {
+		log.Printf("Audience scoring failed: %v", err)
+		return err
+	}
+	for i, p := range profiles {
+		p.AudienceQuality = &qualities[i].Score
+		p.AudienceFlags = qualities[i].Flags
+	}
+	return nil
+}
+
+// estimatePrices stores the sponsored post fee estimates on the profiles
+func estimatePrices(ctx context.Context, analytics domain.AnalyticsClient, profiles []*models.Influencer, timeout time.Duration) error {
+	grpcCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	prices, err := analytics.GetPriceBatch(grpcCtx, profiles)
+	if err != nil {
+		log.Printf("Price estimation failed: %v", err)
+		return err
+	}
+	for i, p := range profiles {
+		p.Prices = prices[i].ByType
+		p.PriceCurrency = prices[i].Currency
+	}
+	return nil
+}
@@ -3,8 +3,11 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/hammo/influScope/indexer/internal/domain"
@@ -12,30 +15,117 @@ import (
 	"github.com/hammo/influScope/pkg/models"
 )
 
+const (
+	// consumerRetryDelay throttles Next when the broker keeps failing, instead of spinning
+	consumerRetryDelay = time.Second
+	// maxEnrichBatch caps how many queued profiles a worker scores in one analytics call
+	maxEnrichBatch = 50
+	// enrichTimeout bounds each analytics call, a message should not wait long on a slow model
+	enrichTimeout = time.Second
+	// finalFlushTimeout bounds the last bulk request when Shutdown has already run out of time
+	finalFlushTimeout = 5 * time.Second
+)
+
+// FallbackMode is what the indexer does with profiles analytics could not enrich
+type FallbackMode string
+
+const (
+	// FallbackPreviousRate keeps the enrichment already indexed for the profile
+	FallbackPreviousRate FallbackMode = "previous"
+	// FallbackScraperRate keeps the engagement rate supplied by the scraper
+	FallbackScraperRate FallbackMode = "scraper"
+	// FallbackRetry nacks the messages so they are retried once analytics is back
+	FallbackRetry FallbackMode = "retry"
+)
+
+// ParseFallbackMode validates a mode read from the configuration
+func ParseFallbackMode(mode string) (FallbackMode, error) {
+	switch m := FallbackMode(mode); m {
+	case FallbackPreviousRate, FallbackScraperRate, FallbackRetry:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown fallback mode %q, want %s, %s or %s", mode, FallbackPreviousRate, FallbackScraperRate, FallbackRetry)
+	}
+}
+
+var errEnrichmentFailed = errors.New("analytics enrichment failed")
+
 type IndexerService struct {
 	consumer  domain.MessageConsumer
 	analytics domain.AnalyticsClient
 	search    domain.SearchRepository
 	metrics   domain.MetricsTracker
+	workers   int
+	fallback  FallbackMode
+	now       func() time.Time
+
+	// Workers outlive the consume context so in-flight messages can finish after Start
+	// returns; cancelWork aborts them when Shutdown runs out of time.
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+	queues     []chan job
+	wg         sync.WaitGroup
 }
 
-func NewIndexerService(c domain.MessageConsumer, a domain.AnalyticsClient, s domain.SearchRepository, m domain.MetricsTracker) *IndexerService {
+// job is a decoded message waiting for its worker
+type job struct {
+	msg        domain.Message
+	influencer models.Influencer
+}
+
+func NewIndexerService(c domain.MessageConsumer, a domain.AnalyticsClient, s domain.SearchRepository, m domain.MetricsTracker, workers int, fallback FallbackMode) *IndexerService {
+	if workers < 1 {
+		workers = 1
+	}
+	workCtx, cancelWork := context.WithCancel(context.Background())
 	return &IndexerService{
-		consumer:  c,
-		analytics: a,
-		search:    s,
-		metrics:   m,
+		consumer:   c,
+		analytics:  a,
+		search:     s,
+		metrics:    m,
+		workers:    workers,
+		fallback:   fallback,
+		now:        time.Now,
+		workCtx:    workCtx,
+		cancelWork: cancelWork,
 	}
 }
 
+// Start pulls messages and fans them out to the worker pool until ctx is cancelled.
+// Every message for a given influencer goes to the same worker, so two updates to the
+// same creator are enriched and queued for indexing in the order they were received.
+// Call Shutdown once Start returns to finish the messages already handed to workers.
 func (s *IndexerService) Start(ctx context.Context) {
-	log.Println("Indexer listening for profiles...")
+	log.Printf("Indexer listening for profiles with %d workers...", s.workers)
+
+	s.queues = make([]chan job, s.workers)
+	for i := range s.queues {
+		// A small buffer lets the dispatcher run ahead of a busy worker without reordering
+		s.queues[i] = make(chan job, 16)
+		s.wg.Add(1)
+		go s.work(s.queues[i])
+	}
+	defer func() {
+		for _, q := range s.queues {
+			close(q)
+		}
+	}()
 
 	for {
 		msg, err := s.consumer.Next(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				log.Println("Stopped consuming")
+				return
+			}
 			log.Printf("Consumer error: %v", err)
-			continue
+			select {
+			case <-time.After(consumerRetryDelay):
+				continue
+			case <-ctx.Done():
+				log.Println("Stopped consuming")
+				return
+			}
 		}
 
 		var influencer models.Influencer
@@ -47,24 +137,190 @@ func (s *IndexerService) Start(ctx context.Context) {
 			continue
 		}
 
-		// 1. gRPC Enrichment
-		grpcCtx, cancel := context.WithTimeout(ctx, time.Second)
-		rate, err := s.analytics.GetEngagement(grpcCtx, influencer.Username, influencer.Followers, influencer.Platform)
-		cancel()
+		select {
+		case s.queues[s.partition(&influencer)] <- job{msg: msg, influencer: influencer}:
+		case <-ctx.Done():
+			// The owning worker is busy and we are stopping, let another instance take it
+			requeue(msg)
+			log.Println("Stopped consuming")
+			return
+		}
+	}
+}
+
+// Shutdown waits for the workers to finish their queued messages, then flushes the pending
+// bulk request so those messages get acked. If ctx expires first, messages that were not
+// processed yet are requeued untouched and whatever is still pending is flushed, with a short
+// grace period of its own, or nacked.
+func (s *IndexerService) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
 
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("Shutdown deadline reached, requeueing unprocessed messages")
+		s.cancelWork()
+		<-done
+	}
+	defer s.cancelWork()
+
+	// An expired ctx would fail the bulk request and nack every pending item
+	flushCtx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		flushCtx, cancel = context.WithTimeout(context.Background(), finalFlushTimeout)
+		defer cancel()
+	}
+
+	// Messages whose items fail here are nacked through their callbacks
+	if err := s.search.Flush(flushCtx); err != nil {
+		return fmt.Errorf("final flush failed: %w", err)
+	}
+	return nil
+}
+
+// work processes the worker's queue. Whatever is already waiting behind the next message is
+// picked up with it, so a backlog is enriched in batches rather than one round trip per profile.
+func (s *IndexerService) work(jobs <-chan job) {
+	defer s.wg.Done()
+	for j := range jobs {
+		batch := []job{j}
+	collect:
+		for len(batch) < maxEnrichBatch {
+			select {
+			case next, ok := <-jobs:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, next)
+			default:
+				break collect
+			}
+		}
+
+		if s.workCtx.Err() != nil {
+			for _, j := range batch {
+				requeue(j.msg)
+			}
+			continue
+		}
+		s.process(s.workCtx, batch)
+	}
+}
+
+// partition picks the worker owning this influencer
+func (s *IndexerService) partition(influencer *models.Influencer) int {
+	key := influencer.ID
+	if key == "" {
+		key = influencer.Username
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(s.workers))
+}
+
+func (s *IndexerService) process(ctx context.Context, batch []job) {
+	now := s.now().UTC()
+	profiles := make([]*models.Influencer, len(batch))
+	for i := range batch {
+		profiles[i] = &batch[i].influencer
+		profiles[i].IngestedAt = now
+	}
+
+	// 1. gRPC Enrichment, one round trip per model for the whole batch
+	failed := enrich(ctx, s.analytics, profiles, now, enrichTimeout)
+	if failed.any() {
+		if ctx.Err() != nil {
+			// Aborted by shutdown, don't index profiles we could not enrich
+			for _, j := range batch {
+				requeue(j.msg)
+			}
+			return
+		}
+		if s.fallback == FallbackRetry && failed.engagement {
+			// Retried with backoff, then parked in the DLQ if analytics stays down. Without the
+			// engagement rate there is nothing worth indexing; audience or pricing failures alone
+			// are indexed as partial below rather than burning retries.
+			for _, j := range batch {
+				if err := j.msg.Nack(errEnrichmentFailed); err != nil {
+					log.Printf("Failed to NACK message: %v", err)
+				}
+			}
+			return
+		}
+		s.fallBack(ctx, profiles, failed)
+	}
+	for _, p := range profiles {
+		p.EnrichmentStatus = failed.status()
+	}
+
+	for _, j := range batch {
+		s.index(ctx, j)
+	}
+}
+
+// fallBack fills in what analytics could not compute and marks the profiles as degraded.
+// With FallbackPreviousRate, and FallbackRetry which only gets here for partial failures, the
+// failed parts are copied from the stored documents, so an outage does not overwrite good data;
+// profiles indexed for the first time keep the scraper's values.
+func (s *IndexerService) fallBack(ctx context.Context, profiles []*models.Influencer, failed enrichFailures) {
+	var stored map[string]*models.Influencer
+	if s.fallback != FallbackScraperRate {
+		ids := make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			if p.ID != "" {
+				ids = append(ids, p.ID)
+			}
+		}
+
+		lookupCtx, cancel := context.WithTimeout(ctx, time.Second)
+		var err error
+		stored, err = s.search.GetProfiles(lookupCtx, ids)
+		cancel()
 		if err != nil {
-			log.Printf("Analytics Service failed: %v", err)
-			influencer.EngagementRate = 0.0
-		} else {
-			influencer.EngagementRate = rate
+			log.Printf("Reading the previous enrichment failed, keeping the scraper's values: %v", err)
+		}
+	}
+
+	for _, p := range profiles {
+		p.Degraded = true
+		prev, ok := stored[p.ID]
+		if !ok {
+			continue
+		}
+		if failed.engagement {
+			p.EngagementRate = prev.EngagementRate
+			p.AnalyticsModelVersion = prev.AnalyticsModelVersion
+			p.EnrichedAt = prev.EnrichedAt
+		}
+		if failed.audience {
+			p.AudienceQuality = prev.AudienceQuality
+			p.AudienceFlags = prev.AudienceFlags
 		}
+		if failed.prices {
+			p.Prices = prev.Prices
+			p.PriceCurrency = prev.PriceCurrency
+		}
+	}
+}
 
-		// 2. Index to Elasticsearch
-		if err := s.search.IndexProfile(ctx, &influencer); err != nil {
+func (s *IndexerService) index(ctx context.Context, j job) {
+	msg, influencer := j.msg, j.influencer
+
+	// 2. Queue for the next bulk request, the message is only acked once its item is stored
+	s.search.IndexProfile(ctx, &influencer, func(err error) {
+		if err != nil {
 			log.Printf("Elastic Error: %v", err)
 			s.metrics.IncError()
-			// Deliberately NOT acking here to allow broker requeue/DLX strategies
-			continue
+			// Retried with backoff, then parked in the DLQ
+			if nackErr := msg.Nack(err); nackErr != nil {
+				log.Printf("Failed to NACK message: %v", nackErr)
+			}
+			return
 		}
 
 		// 3. Complete and Metrics
@@ -74,5 +330,11 @@ func (s *IndexerService) Start(ctx context.Context) {
 			s.metrics.IncIndexed()
 			fmt.Print(".")
 		}
+	})
+}
+
+func requeue(msg domain.Message) {
+	if err := msg.Requeue(); err != nil {
+		log.Printf("Failed to requeue message: %v", err)
 	}
 }
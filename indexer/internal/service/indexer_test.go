@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,19 +15,45 @@ import (
 // --- MOCKS ---
 
 type mockMessage struct {
-	body     []byte
-	ackCount int
+	mu           sync.Mutex
+	body         []byte
+	ackCount     int
+	nackCount    int
+	requeueCount int
 }
 
 func (m *mockMessage) Body() []byte { return m.body }
-func (m *mockMessage) Ack() error   { m.ackCount++; return nil }
+func (m *mockMessage) Ack() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ackCount++
+	return nil
+}
+func (m *mockMessage) Nack(reason error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nackCount++
+	return nil
+}
+func (m *mockMessage) Requeue() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requeueCount++
+	return nil
+}
 
 type mockConsumer struct {
 	messages []*mockMessage
 	index    int
+	err      error // Returned by every call once the messages are consumed
+	calls    int
 }
 
 func (m *mockConsumer) Next(ctx context.Context) (domain.Message, error) {
+	m.calls++
+	if m.err != nil && m.index >= len(m.messages) {
+		return nil, m.err
+	}
 	if m.index >= len(m.messages) {
 		// Block forever so the service loop doesn't spin infinitely after reading all messages
 		<-ctx.Done()
@@ -37,35 +66,131 @@ func (m *mockConsumer) Next(ctx context.Context) (domain.Message, error) {
 func (m *mockConsumer) Close() error { return nil }
 
 type mockAnalytics struct {
-	rate float64
-	err  error
+	rate       float64
+	version    string
+	quality    int
+	qualityErr error
+	priceErr   error
+	err        error
+	block      map[string]chan struct{} // Holds the call for these usernames until the channel is closed
+	mu         sync.Mutex
+	batches    []int // Size of every batch call
 }
 
-func (m *mockAnalytics) GetEngagement(ctx context.Context, u string, f int, p string) (float64, error) {
-	return m.rate, m.err
+func (m *mockAnalytics) GetEngagement(ctx context.Context, p *models.Influencer) (domain.Engagement, error) {
+	if ch, ok := m.block[p.Username]; ok {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return domain.Engagement{}, ctx.Err()
+		}
+	}
+	return domain.Engagement{Rate: m.rate, ModelVersion: m.version}, m.err
+}
+func (m *mockAnalytics) GetEngagementBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Engagement, error) {
+	m.mu.Lock()
+	m.batches = append(m.batches, len(profiles))
+	m.mu.Unlock()
+
+	scores := make([]domain.Engagement, len(profiles))
+	for i, p := range profiles {
+		score, err := m.GetEngagement(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+func (m *mockAnalytics) GetAudienceQualityBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.AudienceQuality, error) {
+	if m.qualityErr != nil {
+		return nil, m.qualityErr
+	}
+	qualities := make([]domain.AudienceQuality, len(profiles))
+	for i, p := range profiles {
+		// Scored after engagement, so the rate is already on the profile
+		qualities[i] = domain.AudienceQuality{Score: m.quality, Flags: []string{fmt.Sprintf("rate_%.1f", p.EngagementRate)}}
+	}
+	return qualities, nil
+}
+func (m *mockAnalytics) GetPriceBatch(ctx context.Context, profiles []*models.Influencer) ([]domain.Prices, error) {
+	if m.priceErr != nil {
+		return nil, m.priceErr
+	}
+	prices := make([]domain.Prices, len(profiles))
+	for i, p := range profiles {
+		// A post costs the engagement rate times the followers, so tests can see the fresh rate was used
+		expected := p.EngagementRate * float64(p.Followers)
+		prices[i] = domain.Prices{Currency: "USD", ByType: map[string]models.PriceRange{
+			"post": {Low: expected / 2, Expected: expected, High: expected * 2},
+		}}
+	}
+	return prices, nil
 }
 func (m *mockAnalytics) Close() error { return nil }
 
 type mockSearch struct {
+	mu         sync.Mutex
 	savedCount int
+	saved      []models.Influencer
+	onSave     func(*models.Influencer)
 	err        error
+	buffer     bool // Hold outcomes until Flush, like the real bulk indexer
+	pending    []func(error)
+	stored     map[string]*models.Influencer // Documents returned by GetProfiles
 }
 
-func (m *mockSearch) IndexProfile(ctx context.Context, profile *models.Influencer) error {
+// IndexProfile reports the outcome immediately, as if every profile was flushed on its own
+func (m *mockSearch) IndexProfile(ctx context.Context, profile *models.Influencer, done func(error)) {
 	if m.err != nil {
-		return m.err
+		done(m.err)
+		return
 	}
+	m.mu.Lock()
 	m.savedCount++
-	return nil
+	m.saved = append(m.saved, *profile)
+	if m.buffer {
+		m.pending = append(m.pending, done)
+		m.mu.Unlock()
+		return
+	}
+	m.mu.Unlock()
+	if m.onSave != nil {
+		m.onSave(profile)
+	}
+	done(nil)
+}
+// Flush fails every pending item when ctx is already done, like a bulk request that never went out
+func (m *mockSearch) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = nil
+	m.mu.Unlock()
+	err := ctx.Err()
+	for _, done := range pending {
+		done(err)
+	}
+	return err
+}
+
+func (m *mockSearch) GetProfiles(ctx context.Context, ids []string) (map[string]*models.Influencer, error) {
+	profiles := make(map[string]*models.Influencer)
+	for _, id := range ids {
+		if p, ok := m.stored[id]; ok {
+			profiles[id] = p
+		}
+	}
+	return profiles, nil
 }
 
 type mockMetrics struct {
+	mu      sync.Mutex
 	indexed int
 	errors  int
 }
 
-func (m *mockMetrics) IncIndexed() { m.indexed++ }
-func (m *mockMetrics) IncError()   { m.errors++ }
+func (m *mockMetrics) IncIndexed() { m.mu.Lock(); m.indexed++; m.mu.Unlock() }
+func (m *mockMetrics) IncError()   { m.mu.Lock(); m.errors++; m.mu.Unlock() }
 
 // --- TESTS ---
 
@@ -74,19 +199,19 @@ func TestMessageConsumptionFlow(t *testing.T) {
 	msg1 := &mockMessage{body: []byte(`{"username": "user1", "followers": 5000}`)}
 	msg2 := &mockMessage{body: []byte(`{"username": "user2", "followers": 100}`)}
 	consumer := &mockConsumer{messages: []*mockMessage{msg1, msg2}}
-	analytics := &mockAnalytics{rate: 5.5}
+	analytics := &mockAnalytics{rate: 5.5, version: "rules-v1"}
 	search := &mockSearch{}
 	metrics := &mockMetrics{}
 
 	// 2. Init Service
-	svc := NewIndexerService(consumer, analytics, search, metrics)
+	svc := NewIndexerService(consumer, analytics, search, metrics, 2, FallbackPreviousRate)
 
-	// 3. Run Service in background briefly
+	// 3. Run Service briefly, Start returns once the workers are done
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
-	go svc.Start(ctx)
-	<-ctx.Done() // Wait for timeout
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
 
 	// 4. Assertions
 	if search.savedCount != 2 {
@@ -98,6 +223,11 @@ func TestMessageConsumptionFlow(t *testing.T) {
 	if msg1.ackCount != 1 || msg2.ackCount != 1 {
 		t.Errorf("Expected both messages to be ACKed exactly once")
 	}
+	for _, p := range search.saved {
+		if p.EngagementRate != 5.5 || p.AnalyticsModelVersion != "rules-v1" {
+			t.Errorf("Expected %s to be scored 5.5 by rules-v1, got %.1f by %q", p.Username, p.EngagementRate, p.AnalyticsModelVersion)
+		}
+	}
 }
 
 func TestBadJSONMessage(t *testing.T) {
@@ -106,12 +236,12 @@ func TestBadJSONMessage(t *testing.T) {
 	search := &mockSearch{}
 	metrics := &mockMetrics{}
 
-	svc := NewIndexerService(consumer, &mockAnalytics{}, search, metrics)
+	svc := NewIndexerService(consumer, &mockAnalytics{}, search, metrics, 2, FallbackPreviousRate)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	go svc.Start(ctx)
-	<-ctx.Done()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
 
 	// Bad JSON should be acked (discarded) but NOT sent to Elasticsearch
 	if search.savedCount != 0 {
@@ -124,3 +254,507 @@ func TestBadJSONMessage(t *testing.T) {
 		t.Errorf("Expected bad message to be ACKed (discarded)")
 	}
 }
+
+func TestFailedIndexingIsNotAcked(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "abc", "username": "user1"}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{err: errors.New("mapper_parsing_exception")}
+	metrics := &mockMetrics{}
+
+	svc := NewIndexerService(consumer, &mockAnalytics{rate: 3.0}, search, metrics, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	// Only messages whose bulk item succeeded may be acked
+	if msg.ackCount != 0 {
+		t.Errorf("Expected failed message not to be ACKed, got %d acks", msg.ackCount)
+	}
+	// It goes back to the broker for a delayed retry instead
+	if msg.nackCount != 1 {
+		t.Errorf("Expected failed message to be NACKed once, got %d", msg.nackCount)
+	}
+	if metrics.errors != 1 || metrics.indexed != 0 {
+		t.Errorf("Expected 1 error and 0 indexed, got %d and %d", metrics.errors, metrics.indexed)
+	}
+}
+
+func TestSameInfluencerIsProcessedInOrder(t *testing.T) {
+	var messages []*mockMessage
+	for i := 1; i <= 20; i++ {
+		id := []string{"a", "b", "c"}[i%3]
+		body := fmt.Sprintf(`{"id": %q, "username": %q, "followers": %d}`, id, id, i)
+		messages = append(messages, &mockMessage{body: []byte(body)})
+	}
+	search := &mockSearch{}
+
+	svc := NewIndexerService(&mockConsumer{messages: messages}, &mockAnalytics{rate: 1.0}, search, &mockMetrics{}, 4, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 20 {
+		t.Fatalf("Expected 20 profiles saved, got %d", len(search.saved))
+	}
+	// Followers grow with every update, so each ID must see them increasing
+	last := map[string]int{}
+	for _, p := range search.saved {
+		if p.Followers < last[p.ID] {
+			t.Errorf("Update %d for %s applied after update %d", p.Followers, p.ID, last[p.ID])
+		}
+		last[p.ID] = p.Followers
+	}
+}
+
+func TestSlowProfileDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := &mockMessage{body: []byte(`{"id": "slow", "username": "slow"}`)}
+	// Pick an ID owned by another worker than "slow"
+	svc := NewIndexerService(nil, nil, nil, nil, 2, FallbackPreviousRate)
+	fastID := "fast"
+	for i := 1; svc.partition(&models.Influencer{ID: fastID}) == svc.partition(&models.Influencer{ID: "slow"}); i++ {
+		fastID = fmt.Sprintf("fast-%d", i)
+	}
+	fast := &mockMessage{body: []byte(fmt.Sprintf(`{"id": %q, "username": "fast"}`, fastID))}
+
+	analytics := &mockAnalytics{rate: 1.0, block: map[string]chan struct{}{"slow": release}}
+	search := &mockSearch{onSave: func(p *models.Influencer) {
+		// The fast profile gets through while the slow one is still stuck in analytics
+		if p.ID == fastID {
+			close(release)
+		}
+	}}
+	metrics := &mockMetrics{}
+
+	svc = NewIndexerService(&mockConsumer{messages: []*mockMessage{slow, fast}}, analytics, search, metrics, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if metrics.indexed != 2 {
+		t.Fatalf("Expected both profiles indexed, got %d", metrics.indexed)
+	}
+	if search.saved[0].ID != fastID {
+		t.Errorf("Expected %s to be indexed before the slow profile, got %s first", fastID, search.saved[0].ID)
+	}
+}
+
+func TestShutdownFlushesPendingWrites(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "abc", "username": "user1"}`)}
+	search := &mockSearch{buffer: true}
+	metrics := &mockMetrics{}
+
+	svc := NewIndexerService(&mockConsumer{messages: []*mockMessage{msg}}, &mockAnalytics{rate: 2.0}, search, metrics, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+
+	// Stopping consumption alone leaves the message waiting in the bulk buffer.
+	// Workers may still be running, hence the locks.
+	time.Sleep(20 * time.Millisecond)
+	search.mu.Lock()
+	msg.mu.Lock()
+	saved, acked := search.savedCount, msg.ackCount
+	msg.mu.Unlock()
+	search.mu.Unlock()
+	if saved != 1 || acked != 0 {
+		t.Fatalf("Expected 1 buffered profile and no ACK yet, got %d and %d", saved, acked)
+	}
+
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	if msg.ackCount != 1 || metrics.indexed != 1 {
+		t.Errorf("Expected the message to be ACKed by the final flush, got %d acks", msg.ackCount)
+	}
+}
+
+func TestShutdownDeadlineRequeuesUnprocessedMessages(t *testing.T) {
+	never := make(chan struct{})
+	stuck := &mockMessage{body: []byte(`{"id": "a", "username": "stuck"}`)}
+	queued := &mockMessage{body: []byte(`{"id": "b", "username": "queued"}`)}
+	analytics := &mockAnalytics{rate: 1.0, block: map[string]chan struct{}{"stuck": never}}
+	search := &mockSearch{}
+
+	// A single worker, so the second message waits behind the stuck one
+	svc := NewIndexerService(&mockConsumer{messages: []*mockMessage{stuck, queued}}, analytics, search, &mockMetrics{}, 1, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShutdown()
+	svc.Shutdown(shutdownCtx)
+
+	// Neither was indexed: both go back to the queue without burning a retry
+	if search.savedCount != 0 {
+		t.Errorf("Expected nothing indexed, got %d", search.savedCount)
+	}
+	for _, msg := range []*mockMessage{stuck, queued} {
+		if msg.requeueCount != 1 || msg.ackCount != 0 || msg.nackCount != 0 {
+			t.Errorf("Expected message to be requeued once, got %d requeues, %d acks, %d nacks", msg.requeueCount, msg.ackCount, msg.nackCount)
+		}
+	}
+}
+
+func TestShutdownDeadlineStillFlushesPendingWrites(t *testing.T) {
+	never := make(chan struct{})
+	stuck := &mockMessage{body: []byte(`{"id": "stuck", "username": "stuck"}`)}
+	// Pick an ID owned by another worker than "stuck"
+	svc := NewIndexerService(nil, nil, nil, nil, 2, FallbackPreviousRate)
+	doneID := "done"
+	for i := 1; svc.partition(&models.Influencer{ID: doneID}) == svc.partition(&models.Influencer{ID: "stuck"}); i++ {
+		doneID = fmt.Sprintf("done-%d", i)
+	}
+	done := &mockMessage{body: []byte(fmt.Sprintf(`{"id": %q, "username": "done"}`, doneID))}
+
+	analytics := &mockAnalytics{rate: 1.0, block: map[string]chan struct{}{"stuck": never}}
+	search := &mockSearch{buffer: true}
+	metrics := &mockMetrics{}
+
+	svc = NewIndexerService(&mockConsumer{messages: []*mockMessage{stuck, done}}, analytics, search, metrics, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+
+	// The stuck profile holds Shutdown past its deadline while the other waits in the bulk buffer
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShutdown()
+	if err := svc.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Expected the final flush to succeed past the deadline, got %v", err)
+	}
+
+	if done.ackCount != 1 || done.nackCount != 0 || metrics.indexed != 1 {
+		t.Errorf("Expected the buffered message to be flushed and acked, got %d acks, %d nacks", done.ackCount, done.nackCount)
+	}
+	if stuck.requeueCount != 1 {
+		t.Errorf("Expected the stuck message to be requeued, got %d requeues", stuck.requeueCount)
+	}
+}
+
+func TestConsumerErrorsAreThrottled(t *testing.T) {
+	consumer := &mockConsumer{err: errors.New("channel closed")}
+
+	svc := NewIndexerService(consumer, &mockAnalytics{}, &mockSearch{}, &mockMetrics{}, 1, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if consumer.calls > 1 {
+		t.Errorf("Expected the loop to back off after a consumer error, got %d calls", consumer.calls)
+	}
+}
+
+func TestBacklogIsEnrichedInBatches(t *testing.T) {
+	var messages []*mockMessage
+	for i := 0; i < 30; i++ {
+		messages = append(messages, &mockMessage{body: []byte(fmt.Sprintf(`{"id": "same", "username": "u", "followers": %d}`, i))})
+	}
+	release := make(chan struct{})
+	// The first call holds the worker until the rest of the backlog is queued behind it
+	analytics := &mockAnalytics{rate: 4.2, block: map[string]chan struct{}{"u": release}}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	search := &mockSearch{}
+
+	svc := NewIndexerService(&mockConsumer{messages: messages}, analytics, search, &mockMetrics{}, 1, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if search.savedCount != 30 {
+		t.Fatalf("Expected 30 profiles saved, got %d", search.savedCount)
+	}
+	if len(analytics.batches) >= 30 {
+		t.Errorf("Expected the backlog to be scored in batches, got %d calls", len(analytics.batches))
+	}
+	for _, p := range search.saved {
+		if p.EngagementRate != 4.2 {
+			t.Errorf("Expected every profile to get its rate, got %.1f", p.EngagementRate)
+		}
+	}
+}
+
+func TestAudienceQualityIsStored(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 5000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+
+	svc := NewIndexerService(consumer, &mockAnalytics{rate: 4.2, quality: 85}, search, &mockMetrics{}, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 {
+		t.Fatalf("Expected 1 profile saved, got %d", len(search.saved))
+	}
+	p := search.saved[0]
+	if p.AudienceQuality == nil || *p.AudienceQuality != 85 {
+		t.Fatalf("Expected an audience quality of 85, got %v", p.AudienceQuality)
+	}
+	if len(p.AudienceFlags) != 1 || p.AudienceFlags[0] != "rate_4.2" {
+		t.Errorf("Expected audience scoring to see the fresh engagement rate, got %v", p.AudienceFlags)
+	}
+}
+
+func TestAudienceScoringFailureStillIndexes(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 5000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+	analytics := &mockAnalytics{rate: 4.2, qualityErr: errors.New("unimplemented")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 || msg.ackCount != 1 {
+		t.Fatalf("Expected the profile to be indexed and acked, got %d saved, %d acks", len(search.saved), msg.ackCount)
+	}
+	if p := search.saved[0]; p.AudienceQuality != nil || p.EngagementRate != 4.2 {
+		t.Errorf("Expected the engagement rate without an audience score, got %.1f / %v", p.EngagementRate, p.AudienceQuality)
+	}
+}
+
+func TestPricesAreStored(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+
+	svc := NewIndexerService(consumer, &mockAnalytics{rate: 2.0}, search, &mockMetrics{}, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 {
+		t.Fatalf("Expected 1 profile saved, got %d", len(search.saved))
+	}
+	p := search.saved[0]
+	if p.PriceCurrency != "USD" || p.Prices["post"].Expected != 2000 {
+		t.Errorf("Expected a $2000 post priced from the fresh rate, got %v %s", p.Prices, p.PriceCurrency)
+	}
+}
+
+func TestPriceEstimationFailureStillIndexes(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+	analytics := &mockAnalytics{rate: 2.0, quality: 90, priceErr: errors.New("unavailable")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 2, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 || msg.ackCount != 1 {
+		t.Fatalf("Expected the profile to be indexed and acked, got %d saved, %d acks", len(search.saved), msg.ackCount)
+	}
+	if p := search.saved[0]; p.Prices != nil || p.AudienceQuality == nil {
+		t.Errorf("Expected the other enrichments without prices, got %v / %v", p.Prices, p.AudienceQuality)
+	}
+}
+
+func TestAnalyticsOutageKeepsPreviousEnrichment(t *testing.T) {
+	known := &mockMessage{body: []byte(`{"id": "1", "username": "known", "followers": 1000, "engagement_rate": 1.5}`)}
+	fresh := &mockMessage{body: []byte(`{"id": "2", "username": "fresh", "followers": 1000, "engagement_rate": 1.5}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{known, fresh}}
+	quality := 80
+	enrichedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	search := &mockSearch{stored: map[string]*models.Influencer{
+		"1": {ID: "1", EngagementRate: 3.3, AnalyticsModelVersion: "rules-v1", AudienceQuality: &quality, EnrichedAt: enrichedAt},
+	}}
+	analytics := &mockAnalytics{err: errors.New("unavailable")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 1, FallbackPreviousRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 2 || known.ackCount != 1 || fresh.ackCount != 1 {
+		t.Fatalf("Expected both profiles indexed and acked, got %d saved", len(search.saved))
+	}
+	for _, p := range search.saved {
+		if !p.Degraded || p.EnrichmentStatus != models.EnrichmentFailed {
+			t.Errorf("Expected %s to be marked degraded and failed, got %q", p.Username, p.EnrichmentStatus)
+		}
+		switch p.Username {
+		case "known":
+			if p.EngagementRate != 3.3 || p.AnalyticsModelVersion != "rules-v1" || p.AudienceQuality == nil || *p.AudienceQuality != 80 {
+				t.Errorf("Expected the stored enrichment to be kept, got %.1f by %q", p.EngagementRate, p.AnalyticsModelVersion)
+			}
+			if !p.EnrichedAt.Equal(enrichedAt) {
+				t.Errorf("Expected the kept enrichment to keep its time, got %v", p.EnrichedAt)
+			}
+		case "fresh":
+			// Never indexed before, the scraper's rate is all there is
+			if p.EngagementRate != 1.5 || p.AnalyticsModelVersion != "" || !p.EnrichedAt.IsZero() {
+				t.Errorf("Expected the scraper's rate, got %.1f by %q at %v", p.EngagementRate, p.AnalyticsModelVersion, p.EnrichedAt)
+			}
+		}
+	}
+}
+
+func TestAnalyticsOutageKeepsScraperRate(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000, "engagement_rate": 1.5}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{stored: map[string]*models.Influencer{"1": {ID: "1", EngagementRate: 3.3}}}
+	analytics := &mockAnalytics{err: errors.New("unavailable")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 1, FallbackScraperRate)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 {
+		t.Fatalf("Expected 1 profile saved, got %d", len(search.saved))
+	}
+	if p := search.saved[0]; p.EngagementRate != 1.5 || !p.Degraded {
+		t.Errorf("Expected the scraper's rate marked as degraded, got %.1f (degraded %v)", p.EngagementRate, p.Degraded)
+	}
+}
+
+func TestAnalyticsOutageHoldsMessagesForRetry(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000, "engagement_rate": 1.5}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+	analytics := &mockAnalytics{err: errors.New("unavailable")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 1, FallbackRetry)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 0 {
+		t.Errorf("Expected nothing indexed while analytics is down, got %d", len(search.saved))
+	}
+	if msg.nackCount != 1 || msg.ackCount != 0 {
+		t.Errorf("Expected the message to be nacked for retry, got %d nacks, %d acks", msg.nackCount, msg.ackCount)
+	}
+}
+
+func TestPartialFailureIsIndexedInRetryMode(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	quality := 80
+	search := &mockSearch{stored: map[string]*models.Influencer{"1": {ID: "1", AudienceQuality: &quality}}}
+	analytics := &mockAnalytics{rate: 2.0, qualityErr: errors.New("unavailable")}
+
+	svc := NewIndexerService(consumer, analytics, search, &mockMetrics{}, 1, FallbackRetry)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	// The engagement rate is fresh, only the audience score is missing: no retry is spent on it
+	if len(search.saved) != 1 || msg.ackCount != 1 || msg.nackCount != 0 {
+		t.Fatalf("Expected the profile to be indexed and acked, got %d saved, %d acks, %d nacks", len(search.saved), msg.ackCount, msg.nackCount)
+	}
+	p := search.saved[0]
+	if p.EnrichmentStatus != models.EnrichmentPartial || !p.Degraded || p.EngagementRate != 2.0 {
+		t.Errorf("Expected a degraded partial profile with the fresh rate, got %q (degraded %v) at %.1f", p.EnrichmentStatus, p.Degraded, p.EngagementRate)
+	}
+	if p.AudienceQuality == nil || *p.AudienceQuality != 80 {
+		t.Errorf("Expected the stored audience quality to be kept, got %v", p.AudienceQuality)
+	}
+}
+
+func TestSuccessfulEnrichmentIsNotDegraded(t *testing.T) {
+	msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000}`)}
+	consumer := &mockConsumer{messages: []*mockMessage{msg}}
+	search := &mockSearch{}
+
+	svc := NewIndexerService(consumer, &mockAnalytics{rate: 2.0}, search, &mockMetrics{}, 1, FallbackRetry)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	svc.Start(ctx)
+	svc.Shutdown(context.Background())
+
+	if len(search.saved) != 1 || search.saved[0].Degraded {
+		t.Errorf("Expected one fully enriched profile, got %+v", search.saved)
+	}
+}
+
+func TestParseFallbackMode(t *testing.T) {
+	for _, mode := range []string{"previous", "scraper", "retry"} {
+		if _, err := ParseFallbackMode(mode); err != nil {
+			t.Errorf("Expected %q to be accepted, got %v", mode, err)
+		}
+	}
+	if _, err := ParseFallbackMode("zero"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
+
+func TestEnrichmentStatusIsRecorded(t *testing.T) {
+	tests := []struct {
+		name      string
+		analytics *mockAnalytics
+		status    string
+		enriched  bool
+	}{
+		{"complete", &mockAnalytics{rate: 2.0}, models.EnrichmentComplete, true},
+		{"partial", &mockAnalytics{rate: 2.0, priceErr: errors.New("unavailable")}, models.EnrichmentPartial, true},
+		{"failed", &mockAnalytics{err: errors.New("unavailable")}, models.EnrichmentFailed, false},
+	}
+
+	now := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &mockMessage{body: []byte(`{"id": "1", "username": "user1", "followers": 1000}`)}
+			search := &mockSearch{}
+
+			svc := NewIndexerService(&mockConsumer{messages: []*mockMessage{msg}}, tt.analytics, search, &mockMetrics{}, 1, FallbackScraperRate)
+			svc.now = func() time.Time { return now }
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			svc.Start(ctx)
+			svc.Shutdown(context.Background())
+
+			if len(search.saved) != 1 {
+				t.Fatalf("Expected 1 profile saved, got %d", len(search.saved))
+			}
+			p := search.saved[0]
+			if p.EnrichmentStatus != tt.status {
+				t.Errorf("Expected status %q, got %q", tt.status, p.EnrichmentStatus)
+			}
+			if !p.IngestedAt.Equal(now) {
+				t.Errorf("Expected ingested_at %v, got %v", now, p.IngestedAt)
+			}
+			if p.EnrichedAt.Equal(now) != tt.enriched {
+				t.Errorf("Expected enriched_at to be set: %v, got %v", tt.enriched, p.EnrichedAt)
+			}
+		})
+	}
+}
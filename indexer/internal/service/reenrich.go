@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// reenrichTimeout bounds each analytics call of a re-enrichment batch, which is larger than
+// what the live indexer sends
+const reenrichTimeout = 10 * time.Second
+
+// RateBuckets are the upper bounds (in %) of the engagement rate histogram in a ReenrichReport
+var RateBuckets = []float64{1, 2, 3, 5, 10}
+
+// ReenrichConfig tunes a re-enrichment run
+type ReenrichConfig struct {
+	BatchSize int     // Profiles read and scored per round trip
+	Rate      float64 // Maximum profiles per second, 0 for no throttling
+	DryRun    bool    // Score without writing, to preview the effect of a model change
+}
+
+// Checkpoint is how far a re-enrichment run got. It is saved after every batch, so an
+// interrupted run resumes after the last profile written.
+type Checkpoint struct {
+	LastID    string `json:"last_id"`
+	Scanned   int    `json:"scanned"`
+	Updated   int    `json:"updated"`
+	Conflicts int    `json:"conflicts"` // Profiles the live indexer rewrote while they were being scored
+}
+
+// ReenrichReport is the outcome of a run. The counters include the runs it resumed, the
+// comparison of the rates before and after only covers this one.
+type ReenrichReport struct {
+	Checkpoint
+	Changed  int // Profiles whose engagement rate changed
+	Before   Distribution
+	After    Distribution
+	Versions map[string]int // Profiles per "old -> new" analytics model version
+}
+
+// Distribution is a histogram of engagement rates: Counts[i] is the number of rates below
+// RateBuckets[i] (and above the previous bound), the last count holds the rest
+type Distribution struct {
+	Counts []int
+	Sum    float64
+	Total  int
+}
+
+func newDistribution() Distribution {
+	return Distribution{Counts: make([]int, len(RateBuckets)+1)}
+}
+
+func (d *Distribution) add(rate float64) {
+	i := 0
+	for i < len(RateBuckets) && rate >= RateBuckets[i] {
+		i++
+	}
+	d.Counts[i]++
+	d.Sum += rate
+	d.Total++
+}
+
+// Mean is the average rate, 0 for an empty distribution
+func (d Distribution) Mean() float64 {
+	if d.Total == 0 {
+		return 0
+	}
+	return d.Sum / float64(d.Total)
+}
+
+// Reenricher re-scores the profiles already indexed, e.g. after the analytics model changed.
+// Only the analytics fields are rewritten, the live indexer keeps running meanwhile.
+type Reenricher struct {
+	store     domain.ProfileStore
+	analytics domain.AnalyticsClient
+	cfg       ReenrichConfig
+	now       func() time.Time
+}
+
+func NewReenricher(store domain.ProfileStore, analytics domain.AnalyticsClient, cfg ReenrichConfig) *Reenricher {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	return &Reenricher{store: store, analytics: analytics, cfg: cfg, now: time.Now}
+}
+
+// Run re-scores every profile after the checkpoint, in ID order. save receives the new
+// checkpoint after each batch is written (never in dry-run mode). The run stops at the first
+// error from analytics or Elasticsearch; the last saved checkpoint is where the next one resumes.
+func (r *Reenricher) Run(ctx context.Context, from Checkpoint, save func(Checkpoint) error) (*ReenrichReport, error) {
+	report := &ReenrichReport{
+		Checkpoint: from,
+		Before:     newDistribution(),
+		After:      newDistribution(),
+		Versions:   map[string]int{},
+	}
+
+	// Throttle by spacing the batches, so analytics sees at most Rate profiles per second
+	var interval time.Duration
+	if r.cfg.Rate > 0 {
+		interval = time.Duration(float64(r.cfg.BatchSize) / r.cfg.Rate * float64(time.Second))
+	}
+
+	for {
+		start := time.Now()
+
+		// 1. Read the next page
+		stored, err := r.store.ScanProfiles(ctx, report.LastID, r.cfg.BatchSize)
+		if err != nil {
+			return report, fmt.Errorf("scanning after %q: %w", report.LastID, err)
+		}
+		if len(stored) == 0 {
+			return report, nil
+		}
+
+		// 2. Score it, remembering what the profiles held before
+		profiles := make([]*models.Influencer, len(stored))
+		oldRates := make([]float64, len(stored))
+		oldVersions := make([]string, len(stored))
+		for i, sp := range stored {
+			profiles[i] = sp.Profile
+			oldRates[i] = sp.Profile.EngagementRate
+			oldVersions[i] = sp.Profile.AnalyticsModelVersion
+		}
+
+		failed := enrich(ctx, r.analytics, profiles, r.now().UTC(), reenrichTimeout)
+		if failed.engagement {
+			return report, fmt.Errorf("scoring after %q: %w", report.LastID, failed.err)
+		}
+		for i, p := range profiles {
+			// Audience quality or prices that failed keep their stored values
+			p.EnrichmentStatus = failed.status()
+			p.Degraded = failed.any()
+
+			report.Before.add(oldRates[i])
+			report.After.add(p.EngagementRate)
+			if p.EngagementRate != oldRates[i] {
+				report.Changed++
+			}
+			report.Versions[versionLabel(oldVersions[i])+" -> "+versionLabel(p.AnalyticsModelVersion)]++
+		}
+		report.Scanned += len(stored)
+
+		// 3. Write and checkpoint
+		if !r.cfg.DryRun {
+			conflicts, err := r.store.UpdateEnrichment(ctx, stored)
+			if err != nil {
+				return report, fmt.Errorf("updating after %q: %w", report.LastID, err)
+			}
+			report.Updated += len(stored) - conflicts
+			report.Conflicts += conflicts
+		}
+		report.LastID = stored[len(stored)-1].Profile.ID
+		if !r.cfg.DryRun {
+			if err := save(report.Checkpoint); err != nil {
+				return report, fmt.Errorf("saving checkpoint: %w", err)
+			}
+		}
+
+		if len(stored) < r.cfg.BatchSize {
+			return report, nil
+		}
+		if wait := interval - time.Since(start); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return report, ctx.Err()
+			}
+		}
+	}
+}
+
+// versionLabel names a model version in the report, profiles never scored have none
+func versionLabel(version string) string {
+	if version == "" {
+		return "none"
+	}
+	return version
+}
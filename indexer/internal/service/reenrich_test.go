@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/indexer/internal/domain"
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// mockStore holds profiles by ID and records the updates it receives
+type mockStore struct {
+	profiles  map[string]models.Influencer
+	conflicts map[string]bool // IDs rewritten by the live indexer since they were read
+	scans     []string        // afterID of every scan
+	updated   []models.Influencer
+}
+
+func newMockStore(rates ...float64) *mockStore {
+	store := &mockStore{profiles: map[string]models.Influencer{}, conflicts: map[string]bool{}}
+	for i, rate := range rates {
+		id := fmt.Sprintf("id-%02d", i)
+		store.profiles[id] = models.Influencer{ID: id, Followers: 1000, EngagementRate: rate, AnalyticsModelVersion: "rules-v1"}
+	}
+	return store
+}
+
+func (m *mockStore) ScanProfiles(ctx context.Context, afterID string, size int) ([]domain.StoredProfile, error) {
+	m.scans = append(m.scans, afterID)
+	ids := make([]string, 0, len(m.profiles))
+	for id := range m.profiles {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) > size {
+		ids = ids[:size]
+	}
+
+	page := make([]domain.StoredProfile, len(ids))
+	for i, id := range ids {
+		p := m.profiles[id]
+		page[i] = domain.StoredProfile{Profile: &p, SeqNo: int64(i)}
+	}
+	return page, nil
+}
+
+func (m *mockStore) UpdateEnrichment(ctx context.Context, profiles []domain.StoredProfile) (int, error) {
+	conflicts := 0
+	for _, sp := range profiles {
+		if m.conflicts[sp.Profile.ID] {
+			conflicts++
+			continue
+		}
+		m.updated = append(m.updated, *sp.Profile)
+	}
+	return conflicts, nil
+}
+
+func TestReenrichRescoresEveryProfile(t *testing.T) {
+	store := newMockStore(0.5, 1.5, 2.5, 7)
+	store.conflicts["id-03"] = true
+	analytics := &mockAnalytics{rate: 4.0, version: "rules-v2", quality: 90}
+
+	var checkpoints []Checkpoint
+	r := NewReenricher(store, analytics, ReenrichConfig{BatchSize: 3})
+	report, err := r.Run(context.Background(), Checkpoint{}, func(c Checkpoint) error {
+		checkpoints = append(checkpoints, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(store.updated) != 3 || report.Updated != 3 || report.Conflicts != 1 || report.Scanned != 4 {
+		t.Fatalf("Expected 3 updates and 1 conflict out of 4, got %+v", report.Checkpoint)
+	}
+	for _, p := range store.updated {
+		if p.EngagementRate != 4.0 || p.AnalyticsModelVersion != "rules-v2" || p.EnrichmentStatus != models.EnrichmentComplete || p.EnrichedAt.IsZero() {
+			t.Errorf("Expected %s to be re-scored by rules-v2, got %+v", p.ID, p)
+		}
+		if p.AudienceQuality == nil || *p.AudienceQuality != 90 {
+			t.Errorf("Expected %s to get a fresh audience score, got %v", p.ID, p.AudienceQuality)
+		}
+	}
+
+	// One checkpoint per batch, each after the last profile of the batch
+	if len(checkpoints) != 2 || checkpoints[0].LastID != "id-02" || checkpoints[1].LastID != "id-03" {
+		t.Errorf("Expected checkpoints after id-02 and id-03, got %+v", checkpoints)
+	}
+
+	if report.Changed != 4 || report.Versions["rules-v1 -> rules-v2"] != 4 {
+		t.Errorf("Expected 4 rates moved from rules-v1 to rules-v2, got %d %v", report.Changed, report.Versions)
+	}
+	if want := []int{1, 1, 1, 0, 1, 0}; fmt.Sprint(report.Before.Counts) != fmt.Sprint(want) {
+		t.Errorf("Expected the old rates in buckets %v, got %v", want, report.Before.Counts)
+	}
+	if want := []int{0, 0, 0, 4, 0, 0}; fmt.Sprint(report.After.Counts) != fmt.Sprint(want) {
+		t.Errorf("Expected the new rates in buckets %v, got %v", want, report.After.Counts)
+	}
+	if report.Before.Mean() != 2.875 || report.After.Mean() != 4.0 {
+		t.Errorf("Expected means 2.875 -> 4.0, got %v -> %v", report.Before.Mean(), report.After.Mean())
+	}
+}
+
+func TestReenrichResumesFromCheckpoint(t *testing.T) {
+	store := newMockStore(1, 2, 3)
+	r := NewReenricher(store, &mockAnalytics{rate: 4.0}, ReenrichConfig{BatchSize: 10})
+
+	report, err := r.Run(context.Background(), Checkpoint{LastID: "id-01", Scanned: 2, Updated: 2}, func(Checkpoint) error { return nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if store.scans[0] != "id-01" {
+		t.Errorf("Expected the scan to start after the checkpoint, got %q", store.scans[0])
+	}
+	if len(store.updated) != 1 || store.updated[0].ID != "id-02" {
+		t.Fatalf("Expected only id-02 to be updated, got %+v", store.updated)
+	}
+	if report.Scanned != 3 || report.Updated != 3 {
+		t.Errorf("Expected the counters to carry on from the checkpoint, got %+v", report.Checkpoint)
+	}
+}
+
+func TestReenrichDryRunWritesNothing(t *testing.T) {
+	store := newMockStore(1, 2, 3)
+	saved := 0
+	r := NewReenricher(store, &mockAnalytics{rate: 2.0, version: "rules-v2"}, ReenrichConfig{BatchSize: 2, DryRun: true})
+
+	report, err := r.Run(context.Background(), Checkpoint{}, func(Checkpoint) error { saved++; return nil })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(store.updated) != 0 || saved != 0 || report.Updated != 0 {
+		t.Errorf("Expected no writes and no checkpoint, got %d updates, %d saves", len(store.updated), saved)
+	}
+	// Rates of 1 and 3 move to 2, the one already at 2 does not
+	if report.Scanned != 3 || report.Changed != 2 {
+		t.Errorf("Expected 2 of 3 rates to change, got %d of %d", report.Changed, report.Scanned)
+	}
+}
+
+func TestReenrichStopsWhenAnalyticsFails(t *testing.T) {
+	store := newMockStore(1, 2)
+	saved := 0
+	r := NewReenricher(store, &mockAnalytics{err: errors.New("unavailable")}, ReenrichConfig{BatchSize: 10})
+
+	_, err := r.Run(context.Background(), Checkpoint{}, func(Checkpoint) error { saved++; return nil })
+	if err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("Expected the analytics error, got %v", err)
+	}
+	if len(store.updated) != 0 || saved != 0 {
+		t.Errorf("Expected nothing written or checkpointed, got %d updates, %d saves", len(store.updated), saved)
+	}
+}
+
+func TestReenrichIsThrottled(t *testing.T) {
+	store := newMockStore(1, 2, 3)
+	// One profile per batch at 20 profiles/s: batches start 50ms apart
+	r := NewReenricher(store, &mockAnalytics{rate: 2.0}, ReenrichConfig{BatchSize: 1, Rate: 20})
+
+	start := time.Now()
+	if _, err := r.Run(context.Background(), Checkpoint{}, func(Checkpoint) error { return nil }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected 3 full batches and an empty one to take at least 150ms, took %v", elapsed)
+	}
+}
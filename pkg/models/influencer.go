@@ -1,5 +1,11 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 // Shared struct used by Scraper (Writer) and Indexer (Reader)
 type Influencer struct {
 	ID             string  `json:"id"`
@@ -10,4 +16,77 @@ type Influencer struct {
 	Bio            string  `json:"bio"`
 	EngagementRate float64 `json:"engagement_rate"`
 	AvatarURL      string  `json:"avatar_url"`
+
+	// Activity over the last Posts posts, the inputs of the engagement model.
+	// Views is only reported by video platforms.
+	Likes    int64 `json:"likes,omitempty"`
+	Comments int64 `json:"comments,omitempty"`
+	Shares   int64 `json:"shares,omitempty"`
+	Views    int64 `json:"views,omitempty"`
+	Posts    int   `json:"posts,omitempty"`
+
+	// Audience signals reported by the source, inputs of the audience quality score
+	Following        int     `json:"following,omitempty"`
+	FollowerHistory  []int   `json:"follower_history,omitempty"`  // Daily follower counts, oldest first
+	PostInteractions []int64 `json:"post_interactions,omitempty"` // Interactions on each recent post
+
+	// AudienceQuality goes from 0 (fake) to 100 (authentic) and is nil until analytics scored it.
+	// AudienceFlags lists the signals that lowered it, e.g. follower_spike.
+	AudienceQuality *int     `json:"audience_quality,omitempty"`
+	AudienceFlags   []string `json:"audience_flags,omitempty"`
+
+	// Prices estimates a sponsored post fee per content type (post, story, reel, video)
+	Prices        map[string]PriceRange `json:"prices,omitempty"`
+	PriceCurrency string                `json:"price_currency,omitempty"`
+
+	// AnalyticsModelVersion is the analytics ruleset that computed EngagementRate
+	AnalyticsModelVersion string `json:"analytics_model_version,omitempty"`
+	// Degraded is set when analytics could not enrich the profile and the indexer fell back
+	// to a previous or scraper supplied value
+	Degraded bool `json:"degraded,omitempty"`
+
+	// Set by the indexer: when it processed the event, when analytics last scored the profile
+	// (zero if it never did) and how that went, one of the Enrichment* statuses
+	IngestedAt       time.Time `json:"ingested_at,omitzero"`
+	EnrichedAt       time.Time `json:"enriched_at,omitzero"`
+	EnrichmentStatus string    `json:"enrichment_status,omitempty"`
+
+	// ScrapedAt is when the source observed the profile. The indexer uses it as the
+	// document version, so an older event never overwrites a newer one.
+	ScrapedAt time.Time `json:"scraped_at,omitzero"`
+}
+
+// Enrichment statuses of an indexed profile
+const (
+	EnrichmentComplete = "complete" // Every analytics model scored the profile
+	EnrichmentPartial  = "partial"  // The engagement rate is fresh, audience quality or prices fell back
+	EnrichmentFailed   = "failed"   // Analytics was unavailable, the engagement rate fell back
+)
+
+// PriceRange is the estimated fee of one piece of sponsored content
+type PriceRange struct {
+	Low      float64 `json:"low"`
+	Expected float64 `json:"expected"`
+	High     float64 `json:"high"`
+}
+
+// Validate checks the fields a source provides, reporting every problem at once
+func (i Influencer) Validate() error {
+	var errs []error
+	if i.Username == "" {
+		errs = append(errs, errors.New("username is required"))
+	}
+	if i.Platform == "" {
+		errs = append(errs, errors.New("platform is required"))
+	}
+	if i.Followers < 0 || i.Following < 0 || i.Posts < 0 {
+		errs = append(errs, errors.New("followers, following and posts must not be negative"))
+	}
+	if i.Likes < 0 || i.Comments < 0 || i.Shares < 0 || i.Views < 0 {
+		errs = append(errs, errors.New("likes, comments, shares and views must not be negative"))
+	}
+	if i.EngagementRate < 0 || i.EngagementRate > 100 {
+		errs = append(errs, fmt.Errorf("engagement_rate must be within [0, 100], got %v", i.EngagementRate))
+	}
+	return errors.Join(errs...)
 }
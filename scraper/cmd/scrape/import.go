@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hammo/influScope/scraper/internal/repository"
+	"github.com/hammo/influScope/scraper/internal/service"
+)
+
+// runImport publishes a roster of creators from a CSV or JSON Lines file:
+//
+//	scraper import [-rate N] [-dry-run] FILE
+//
+// Rows use the same columns as the file source. avatar_url may be a local path (relative to
+// the file) or a URL, the image is copied to the avatar bucket. Rejected rows are printed with
+// their line and the command exits with status 1 if there were any.
+func runImport(args []string) {
+	if err := importRoster(args); err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+}
+
+// importRoster runs the import. It returns rather than exits so the file, the connections and
+// the signal handler are released before runImport exits.
+func importRoster(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	rate := fs.Float64("rate", 20, "maximum profiles published per second (0 means unlimited)")
+	dryRun := fs.Bool("dry-run", false, "validate the rows and avatars without uploading or publishing")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("usage: scraper import [-rate N] [-dry-run] FILE")
+	}
+	path := fs.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	source, err := repository.NewFileSource(path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer source.Close()
+
+	// A dry run touches neither S3 nor RabbitMQ
+	var importer *service.Importer
+	fetcher := repository.NewAvatarFetcher(filepath.Dir(path))
+	if *dryRun {
+		importer = service.NewImporter(source, fetcher, nil, nil, service.ImportConfig{DryRun: true})
+	} else {
+		storage, err := newStorage(ctx)
+		if err != nil {
+			return fmt.Errorf("initializing S3: %w", err)
+		}
+		publisher, err := repository.NewRabbitMQPublisher("influencer-events", 10)
+		if err != nil {
+			return fmt.Errorf("initializing the broker: %w", err)
+		}
+		defer publisher.Close()
+		importer = service.NewImporter(source, fetcher, storage, publisher, service.ImportConfig{Rate: *rate})
+	}
+
+	report, err := importer.Run(ctx)
+	for _, rejected := range report.Rejected {
+		fmt.Println(rejected)
+	}
+	log.Printf("%d row(s) read, %d published, %d rejected", report.Rows, report.Published, len(report.Rejected))
+	if err != nil {
+		return fmt.Errorf("stopped: %w", err)
+	}
+	if len(report.Rejected) > 0 {
+		return fmt.Errorf("%d row(s) rejected", len(report.Rejected))
+	}
+	return nil
+}
@@ -2,20 +2,40 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/hammo/influScope/scraper/internal/domain"
 	"github.com/hammo/influScope/scraper/internal/repository"
 	"github.com/hammo/influScope/scraper/internal/service"
 )
 
+// Defaults of the pacing settings, overridable with SCRAPER_INTERVAL and SCRAPER_POLL_INTERVAL
+const (
+	defaultInterval     = time.Second // Between two published profiles
+	defaultPollInterval = time.Minute // Between two fetches of SCRAPER_SOURCE_URL
+)
+
 func main() {
+	// Maintenance subcommands, e.g. `scraper import roster.csv`
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		default:
+			log.Fatalf("Unknown command %q", os.Args[1])
+		}
+	}
+
 	// Note: rand.Seed is deprecated in Go 1.20+, but perfectly fine to leave for now
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -34,22 +54,7 @@ func main() {
 	}()
 
 	// 2. Initialize Repositories
-
-	// Pull the endpoint from Docker Compose environment variables
-	endpoint := os.Getenv("S3_ENDPOINT")
-	if endpoint == "" {
-		// Fallback for when you run it locally outside of Docker
-		endpoint = "http://localhost:9000"
-	}
-
-	// Initialize S3 with the dynamic endpoint (Named 'storage' to match your service)
-	storage, err := repository.NewS3Storage(
-		ctx,
-		endpoint,
-		"avatars",
-		"admin",
-		"password",
-	)
+	storage, err := newStorage(ctx)
 	if err != nil {
 		log.Fatalf("Failed to initialize S3: %v", err)
 	}
@@ -61,8 +66,15 @@ func main() {
 	}
 	defer publisher.Close()
 
+	source, err := newProfileSource()
+	if err != nil {
+		log.Fatalf("Failed to init profile source: %v", err)
+	}
+	defer source.Close()
+
 	// 3. Initialize & Run Service
-	scraperService := service.NewScraperService(storage, publisher, profilesDiscovered)
+	interval := envDuration("SCRAPER_INTERVAL", defaultInterval)
+	scraperService := service.NewScraperService(source, storage, publisher, profilesDiscovered, interval)
 
 	// Handle graceful shutdown
 	go scraperService.Run(ctx)
@@ -72,3 +84,61 @@ func main() {
 	<-sigChan
 	log.Println("Shutting down gracefully...")
 }
+
+// newStorage connects to the avatar bucket
+func newStorage(ctx context.Context) (*repository.S3Storage, error) {
+	// Pull the endpoint from Docker Compose environment variables
+	endpoint := os.Getenv("S3_ENDPOINT")
+	if endpoint == "" {
+		// Fallback for when you run it locally outside of Docker
+		endpoint = "http://localhost:9000"
+	}
+
+	return repository.NewS3Storage(
+		ctx,
+		endpoint,
+		"avatars",
+		"admin",
+		"password",
+	)
+}
+
+// newProfileSource picks where profiles come from with SCRAPER_SOURCE:
+//   - synthetic (default): generated fake profiles
+//   - file: the CSV or JSON Lines file at SCRAPER_SOURCE_PATH
+//   - http: the JSON array served by SCRAPER_SOURCE_URL, polled every SCRAPER_POLL_INTERVAL
+func newProfileSource() (domain.ProfileSource, error) {
+	switch kind := os.Getenv("SCRAPER_SOURCE"); kind {
+	case "", "synthetic":
+		return service.NewSyntheticSource(), nil
+	case "file":
+		path := os.Getenv("SCRAPER_SOURCE_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("SCRAPER_SOURCE_PATH is required for the file source")
+		}
+		log.Printf("Reading profiles from %s", path)
+		return repository.NewFileSource(path)
+	case "http":
+		url := os.Getenv("SCRAPER_SOURCE_URL")
+		if url == "" {
+			return nil, fmt.Errorf("SCRAPER_SOURCE_URL is required for the http source")
+		}
+		log.Printf("Polling profiles from %s", url)
+		return repository.NewHTTPSource(url, envDuration("SCRAPER_POLL_INTERVAL", defaultPollInterval)), nil
+	default:
+		return nil, fmt.Errorf("unknown SCRAPER_SOURCE %q, expected synthetic, file or http", kind)
+	}
+}
+
+// envDuration reads a duration setting such as "500ms", falling back to def when unset
+func envDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		log.Fatalf("Invalid %s %q: expected a duration such as 1s", key, raw)
+	}
+	return d
+}
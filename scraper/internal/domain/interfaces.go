@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/hammo/influScope/pkg/models"
 )
@@ -11,8 +12,44 @@ type AvatarStorage interface {
 	UploadAvatar(ctx context.Context, username string, imageData []byte) (string, error)
 }
 
+// AvatarFetcher loads an avatar image from where a roster points to it, a local path or a URL
+type AvatarFetcher interface {
+	FetchAvatar(ctx context.Context, location string) ([]byte, error)
+}
+
 // EventPublisher handles async message brokering
 type EventPublisher interface {
 	PublishProfile(ctx context.Context, profile models.Influencer) error
 	Close() error
 }
+
+// DiscoveredProfile is a profile found by a source, with the raw avatar image to store when
+// the source provides one instead of an avatar URL
+type DiscoveredProfile struct {
+	Influencer models.Influencer
+	Avatar     []byte
+	Row        int // Line in a file or position in a response, 0 for generated profiles
+}
+
+// ProfileSource is where the scraper discovers profiles: generated, read from a file or
+// polled from an HTTP endpoint
+type ProfileSource interface {
+	// Next blocks until a profile is available. Finite sources return io.EOF once exhausted,
+	// a *RowError reports a single invalid profile the caller can skip.
+	Next(ctx context.Context) (DiscoveredProfile, error)
+	Close() error
+}
+
+// RowError is an invalid profile, Row is its line in a file or position in a response
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
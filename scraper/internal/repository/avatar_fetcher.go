@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxAvatarSize bounds one avatar image
+const maxAvatarSize = 10 << 20
+
+// avatarFetcher reads avatars from http(s) URLs or local files. Relative paths are resolved
+// against baseDir, typically the directory of the imported roster.
+type avatarFetcher struct {
+	client  *http.Client
+	baseDir string
+}
+
+func NewAvatarFetcher(baseDir string) *avatarFetcher {
+	return &avatarFetcher{client: &http.Client{Timeout: 30 * time.Second}, baseDir: baseDir}
+}
+
+func (f *avatarFetcher) FetchAvatar(ctx context.Context, location string) ([]byte, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return f.download(ctx, location)
+	}
+
+	path := location
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(f.baseDir, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return readAvatar(file, path)
+}
+
+func (f *avatarFetcher) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: unexpected status %s", url, res.Status)
+	}
+	return readAvatar(res.Body, url)
+}
+
+// readAvatar reads a whole image, refusing anything empty or larger than maxAvatarSize
+func readAvatar(r io.Reader, location string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxAvatarSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading avatar %s: %w", location, err)
+	}
+	switch {
+	case len(data) == 0:
+		return nil, fmt.Errorf("avatar %s is empty", location)
+	case len(data) > maxAvatarSize:
+		return nil, fmt.Errorf("avatar %s is larger than %d bytes", location, maxAvatarSize)
+	}
+	return data, nil
+}
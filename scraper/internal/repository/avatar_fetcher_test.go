@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAvatarFetcherReadsFilesAndURLs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "alice.jpg"), []byte("local image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bob.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("remote image"))
+	}))
+	defer server.Close()
+
+	fetcher := NewAvatarFetcher(dir)
+	ctx := context.Background()
+
+	// Relative paths are resolved against the roster's directory
+	if data, err := fetcher.FetchAvatar(ctx, "alice.jpg"); err != nil || string(data) != "local image" {
+		t.Errorf("Expected the local file, got %q, %v", data, err)
+	}
+	if data, err := fetcher.FetchAvatar(ctx, server.URL+"/bob.jpg"); err != nil || string(data) != "remote image" {
+		t.Errorf("Expected the downloaded image, got %q, %v", data, err)
+	}
+
+	for _, location := range []string{"missing.jpg", server.URL + "/missing.jpg"} {
+		if _, err := fetcher.FetchAvatar(ctx, location); err == nil {
+			t.Errorf("Expected %s to fail", location)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+// maxLineSize bounds one JSON Lines record
+const maxLineSize = 1 << 20
+
+// fileSource reads profiles from a CSV file with a header row or a JSON Lines file, picked
+// by the extension. Rows are streamed, so a large roster is never loaded at once.
+type fileSource struct {
+	file *os.File
+	now  func() time.Time
+
+	// CSV
+	csv    *csv.Reader
+	header []string
+
+	// JSON Lines
+	lines *bufio.Scanner
+
+	row  int // Line of the record read last
+	done bool
+}
+
+func NewFileSource(path string) (*fileSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &fileSource{file: f, now: time.Now}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		s.csv = csv.NewReader(f)
+		s.csv.TrimLeadingSpace = true
+		s.header, err = s.csv.Read()
+		if err == nil {
+			for i := range s.header {
+				s.header[i] = strings.ToLower(strings.TrimSpace(s.header[i]))
+			}
+			err = checkFields(s.header)
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("reading the header of %s: %w", path, err)
+		}
+	case ".jsonl", ".ndjson":
+		s.lines = bufio.NewScanner(f)
+		s.lines.Buffer(make([]byte, 64*1024), maxLineSize)
+	default:
+		f.Close()
+		return nil, fmt.Errorf("unsupported file %s, expected .csv, .jsonl or .ndjson", path)
+	}
+	return s, nil
+}
+
+// Next returns the next profile. Invalid rows are reported as *domain.RowError and reading
+// can go on; an unreadable file ends the source after its error is returned.
+func (s *fileSource) Next(ctx context.Context) (domain.DiscoveredProfile, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.DiscoveredProfile{}, err
+	}
+	if s.done {
+		return domain.DiscoveredProfile{}, io.EOF
+	}
+	if s.csv != nil {
+		return s.nextCSV()
+	}
+	return s.nextJSON()
+}
+
+func (s *fileSource) nextCSV() (domain.DiscoveredProfile, error) {
+	record, err := s.csv.Read()
+	if err == io.EOF {
+		s.done = true
+		return domain.DiscoveredProfile{}, io.EOF
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// The reader moves on to the next record
+			return domain.DiscoveredProfile{}, &domain.RowError{Row: parseErr.StartLine, Err: parseErr.Err}
+		}
+		s.done = true
+		return domain.DiscoveredProfile{}, err
+	}
+	s.row, _ = s.csv.FieldPos(0)
+
+	profile, err := decodeCSVProfile(s.header, record, s.now())
+	if err != nil {
+		return domain.DiscoveredProfile{}, &domain.RowError{Row: s.row, Err: err}
+	}
+	return domain.DiscoveredProfile{Influencer: profile, Row: s.row}, nil
+}
+
+func (s *fileSource) nextJSON() (domain.DiscoveredProfile, error) {
+	for s.lines.Scan() {
+		s.row++
+		line := bytes.TrimSpace(s.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		profile, err := decodeJSONProfile(line, s.now())
+		if err != nil {
+			return domain.DiscoveredProfile{}, &domain.RowError{Row: s.row, Err: err}
+		}
+		return domain.DiscoveredProfile{Influencer: profile, Row: s.row}, nil
+	}
+
+	s.done = true
+	if err := s.lines.Err(); err != nil {
+		return domain.DiscoveredProfile{}, fmt.Errorf("reading line %d: %w", s.row+1, err)
+	}
+	return domain.DiscoveredProfile{}, io.EOF
+}
+
+func (s *fileSource) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+// readAll drains a source, collecting the profiles and the rows that were rejected
+func readAll(t *testing.T, path string) ([]domain.DiscoveredProfile, []*domain.RowError) {
+	t.Helper()
+	source, err := NewFileSource(path)
+	if err != nil {
+		t.Fatalf("Expected the file to open, got %v", err)
+	}
+	defer source.Close()
+
+	var profiles []domain.DiscoveredProfile
+	var rejected []*domain.RowError
+	for {
+		p, err := source.Next(context.Background())
+		var rowErr *domain.RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			return profiles, rejected
+		case errors.As(err, &rowErr):
+			rejected = append(rejected, rowErr)
+		case err != nil:
+			t.Fatalf("Unexpected error: %v", err)
+		default:
+			profiles = append(profiles, p)
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileSourceReadsCSV(t *testing.T) {
+	path := writeFile(t, "roster.csv", `username,platform,followers,engagement_rate,category,follower_history
+alice,Instagram,12000,3.5,Fashion,11000|11500|12000
+bob,TikTok,lots,2.0,Gaming,
+,YouTube,500,1.0,Tech,
+carol,TikTok,900
+`)
+
+	profiles, rejected := readAll(t, path)
+
+	if len(profiles) != 1 {
+		t.Fatalf("Expected 1 valid profile, got %d", len(profiles))
+	}
+	alice := profiles[0].Influencer
+	if alice.Username != "alice" || alice.Followers != 12000 || alice.EngagementRate != 3.5 || len(alice.FollowerHistory) != 3 {
+		t.Errorf("Unexpected profile %+v", alice)
+	}
+	// No ID in the file: derived from the creator so a re-import updates the same document
+	if profiles[0].Row != 2 {
+		t.Errorf("Expected alice on row 2, got %d", profiles[0].Row)
+	}
+	if alice.ID == "" || alice.ScrapedAt.IsZero() {
+		t.Errorf("Expected an ID and scrape time to be filled in, got %q / %v", alice.ID, alice.ScrapedAt)
+	}
+
+	if len(rejected) != 3 {
+		t.Fatalf("Expected 3 rejected rows, got %v", rejected)
+	}
+	for i, want := range []struct {
+		row int
+		msg string
+	}{{3, "followers"}, {4, "username is required"}, {5, "wrong number of fields"}} {
+		if rejected[i].Row != want.row || !strings.Contains(rejected[i].Error(), want.msg) {
+			t.Errorf("Expected row %d to fail with %q, got %v", want.row, want.msg, rejected[i])
+		}
+	}
+}
+
+func TestFileSourceReadsJSONLines(t *testing.T) {
+	path := writeFile(t, "roster.jsonl", `{"id": "x1", "username": "alice", "platform": "Instagram", "followers": 12000}
+
+{"username": "bob", "platform": "TikTok", "audience_quality": 100}
+{"username": "carol", "platform": "TikTok", "engagement_rate": 150}
+not json
+`)
+
+	profiles, rejected := readAll(t, path)
+
+	if len(profiles) != 1 || profiles[0].Influencer.ID != "x1" {
+		t.Fatalf("Expected only alice to be read, got %+v", profiles)
+	}
+	// Analytics fields cannot be imported
+	if len(rejected) != 3 || rejected[0].Row != 3 || !strings.Contains(rejected[0].Error(), "audience_quality") {
+		t.Fatalf("Expected bob to be rejected for an unknown field, got %v", rejected)
+	}
+	if rejected[1].Row != 4 || rejected[2].Row != 5 {
+		t.Errorf("Expected rows 4 and 5 to be rejected, got %v", rejected)
+	}
+}
+
+func TestFileSourceRejectsUnknownColumns(t *testing.T) {
+	path := writeFile(t, "roster.csv", "username,platform,follwers\nalice,Instagram,100\n")
+	if _, err := NewFileSource(path); err == nil || !strings.Contains(err.Error(), "follwers") {
+		t.Errorf("Expected the misspelt column to be reported, got %v", err)
+	}
+	if _, err := NewFileSource(writeFile(t, "roster.xlsx", "")); err == nil {
+		t.Error("Expected an unsupported extension to be rejected")
+	}
+}
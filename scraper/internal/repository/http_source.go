@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hammo/influScope/pkg/models"
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+// maxResponseSize bounds one poll response
+const maxResponseSize = 32 << 20
+
+// httpSource polls an endpoint returning a JSON array of profiles, e.g. a data vendor's
+// export, and hands them out one at a time. The endpoint is fetched again every interval and
+// only profiles that changed since the previous response are handed out: each one sent again
+// would be a new version of the document and a new metrics snapshot in the creator's history.
+type httpSource struct {
+	client   *http.Client
+	url      string
+	interval time.Duration
+	now      func() time.Time
+
+	pending  []json.RawMessage
+	offset   int // Position in the response of pending[0]
+	lastPoll time.Time
+
+	// Content hash by profile ID, for the previous response and the one being read. Creators
+	// missing from a response are forgotten, so they are handed out again if they come back.
+	previous map[string][sha1.Size]byte
+	seen     map[string][sha1.Size]byte
+}
+
+func NewHTTPSource(url string, interval time.Duration) *httpSource {
+	return &httpSource{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		url:      url,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Next returns the next new or changed profile of the last response, polling when it is used
+// up. A failed poll is returned as is, the next call waits for the interval before retrying.
+func (s *httpSource) Next(ctx context.Context) (domain.DiscoveredProfile, error) {
+	for {
+		for len(s.pending) == 0 {
+			if wait := s.interval - s.now().Sub(s.lastPoll); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return domain.DiscoveredProfile{}, ctx.Err()
+				}
+			}
+			if err := s.poll(ctx); err != nil {
+				return domain.DiscoveredProfile{}, err
+			}
+		}
+
+		raw := s.pending[0]
+		s.pending = s.pending[1:]
+		s.offset++
+
+		// Decoded without a scrape time first, so a profile the vendor did not date hashes
+		// the same on every poll
+		profile, err := decodeJSONProfile(raw, time.Time{})
+		if err != nil {
+			return domain.DiscoveredProfile{}, &domain.RowError{Row: s.offset, Err: err}
+		}
+		if !s.changed(profile) {
+			continue
+		}
+		if profile.ScrapedAt.IsZero() {
+			profile.ScrapedAt = s.now().UTC()
+		}
+		return domain.DiscoveredProfile{Influencer: profile, Row: s.offset}, nil
+	}
+}
+
+// changed records the profile's content and reports whether it differs from the previous response
+func (s *httpSource) changed(profile models.Influencer) bool {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return true
+	}
+	sum := sha1.Sum(data)
+	s.seen[profile.ID] = sum
+
+	prev, ok := s.previous[profile.ID]
+	return !ok || prev != sum
+}
+
+func (s *httpSource) poll(ctx context.Context) error {
+	s.lastPoll = s.now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("polling %s: %w", s.url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("polling %s: unexpected status %s", s.url, res.Status)
+	}
+
+	var profiles []json.RawMessage
+	if err := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&profiles); err != nil {
+		return fmt.Errorf("polling %s: expected a JSON array of profiles: %w", s.url, err)
+	}
+	s.pending, s.offset = profiles, 0
+	if s.seen != nil {
+		s.previous = s.seen
+	}
+	s.seen = make(map[string][sha1.Size]byte, len(profiles))
+	return nil
+}
+
+func (s *httpSource) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
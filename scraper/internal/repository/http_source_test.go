@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+func TestHTTPSourcePollsEndpoint(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// alice gains followers between polls, so she is handed out again
+		poll := polls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `[
+			{"id": "a1", "username": "alice", "platform": "Instagram", "followers": %d, "engagement_rate": 3.1},
+			{"username": "", "platform": "TikTok"}
+		]`, 11000+poll*1000)
+	}))
+	defer server.Close()
+
+	source := NewHTTPSource(server.URL, 20*time.Millisecond)
+	defer source.Close()
+	ctx := context.Background()
+
+	first, err := source.Next(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p := first.Influencer; p.ID != "a1" || p.Username != "alice" || p.Followers != 12000 || p.ScrapedAt.IsZero() {
+		t.Errorf("Expected alice stamped with a scrape time, got %+v", p)
+	}
+
+	_, err = source.Next(ctx)
+	var rowErr *domain.RowError
+	if !errors.As(err, &rowErr) || rowErr.Row != 2 {
+		t.Fatalf("Expected the invalid second profile to be reported, got %v", err)
+	}
+
+	// The response is used up, the next call polls again after the interval
+	start := time.Now()
+	second, err := source.Next(ctx)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if polls.Load() != 2 || time.Since(start) < 10*time.Millisecond {
+		t.Errorf("Expected a second poll after the interval, got %d polls in %v", polls.Load(), time.Since(start))
+	}
+	if second.Influencer.Followers != 13000 {
+		t.Errorf("Expected alice's new follower count, got %d", second.Influencer.Followers)
+	}
+}
+
+func TestHTTPSourceSkipsUnchangedProfiles(t *testing.T) {
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id": "a1", "username": "alice", "platform": "Instagram", "followers": 12000}]`))
+	}))
+	defer server.Close()
+
+	source := NewHTTPSource(server.URL, 5*time.Millisecond)
+	defer source.Close()
+
+	// Read for a few polls of the same payload: alice is only handed out by the first one
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var published int
+	for {
+		if _, err := source.Next(ctx); err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected to stop with the context, got %v", err)
+			}
+			break
+		}
+		published++
+	}
+
+	if polls.Load() < 2 || published != 1 {
+		t.Errorf("Expected one profile out of %d polls of the same payload, got %d", polls.Load(), published)
+	}
+}
+
+func TestHTTPSourceReportsFailedPolls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	source := NewHTTPSource(server.URL, time.Hour)
+	_, err := source.Next(context.Background())
+
+	var rowErr *domain.RowError
+	if err == nil || errors.As(err, &rowErr) {
+		t.Fatalf("Expected the poll failure as a source error, got %v", err)
+	}
+
+	// Waiting for the next poll gives up with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := source.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to end with the context, got %v", err)
+	}
+}
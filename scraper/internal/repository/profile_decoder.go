@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hammo/influScope/pkg/models"
+)
+
+// Kinds of the values a source field holds, used to type CSV cells
+const (
+	kindString = iota
+	kindInt
+	kindFloat
+	kindInts // CSV cells separate the values with '|'
+)
+
+// sourceFields are the models.Influencer fields (by JSON name) a source may set.
+// Everything else is computed downstream by analytics and the indexer.
+var sourceFields = map[string]int{
+	"id":                kindString,
+	"username":          kindString,
+	"platform":          kindString,
+	"category":          kindString,
+	"bio":               kindString,
+	"avatar_url":        kindString,
+	"scraped_at":        kindString, // RFC 3339
+	"followers":         kindInt,
+	"following":         kindInt,
+	"posts":             kindInt,
+	"likes":             kindInt,
+	"comments":          kindInt,
+	"shares":            kindInt,
+	"views":             kindInt,
+	"engagement_rate":   kindFloat,
+	"follower_history":  kindInts,
+	"post_interactions": kindInts,
+}
+
+// checkFields rejects fields a source is not allowed to set, so a typo in a column name
+// does not silently drop the data
+func checkFields(names []string) error {
+	var unknown []string
+	for _, name := range names {
+		if _, ok := sourceFields[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown field(s) %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// decodeJSONProfile decodes one JSON object into a profile
+func decodeJSONProfile(data []byte, now time.Time) (models.Influencer, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return models.Influencer{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	if err := checkFields(names); err != nil {
+		return models.Influencer{}, err
+	}
+
+	var p models.Influencer
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&p); err != nil {
+		return models.Influencer{}, fmt.Errorf("invalid profile: %w", err)
+	}
+	return normalize(p, now)
+}
+
+// decodeCSVProfile types the cells of one CSV record by their header, then decodes them
+// like a JSON object. Empty cells are left unset.
+func decodeCSVProfile(header, record []string, now time.Time) (models.Influencer, error) {
+	fields := make(map[string]interface{}, len(header))
+	for i, name := range header {
+		cell := strings.TrimSpace(record[i])
+		if cell == "" {
+			continue
+		}
+		v, err := parseCell(sourceFields[name], cell)
+		if err != nil {
+			return models.Influencer{}, fmt.Errorf("%s: %w", name, err)
+		}
+		fields[name] = v
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return models.Influencer{}, err
+	}
+	var p models.Influencer
+	if err := json.Unmarshal(data, &p); err != nil {
+		return models.Influencer{}, fmt.Errorf("invalid profile: %w", err)
+	}
+	return normalize(p, now)
+}
+
+func parseCell(kind int, cell string) (interface{}, error) {
+	switch kind {
+	case kindInt:
+		return strconv.ParseInt(cell, 10, 64)
+	case kindFloat:
+		return strconv.ParseFloat(cell, 64)
+	case kindInts:
+		parts := strings.Split(cell, "|")
+		values := make([]int64, len(parts))
+		for i, part := range parts {
+			v, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = v
+		}
+		return values, nil
+	default:
+		return cell, nil
+	}
+}
+
+// normalize fills in what a source may leave out and validates the profile. A missing ID is
+// derived from the platform and username, so importing the same creator twice updates one
+// document; a missing scraped_at means the profile was observed now.
+func normalize(p models.Influencer, now time.Time) (models.Influencer, error) {
+	p.Username = strings.TrimSpace(p.Username)
+	p.Platform = strings.TrimSpace(p.Platform)
+	if err := p.Validate(); err != nil {
+		return models.Influencer{}, err
+	}
+
+	if p.ID == "" {
+		sum := sha1.Sum([]byte(strings.ToLower(p.Platform + ":" + p.Username)))
+		p.ID = hex.EncodeToString(sum[:16])
+	}
+	if p.ScrapedAt.IsZero() {
+		p.ScrapedAt = now.UTC()
+	}
+	return p, nil
+}
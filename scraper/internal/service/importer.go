@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+// ImportConfig tunes an import
+type ImportConfig struct {
+	Rate   float64 // Maximum profiles published per second, 0 for no limit
+	DryRun bool    // Check the rows and fetch the avatars without uploading or publishing
+}
+
+// ImportReport summarises an import
+type ImportReport struct {
+	Rows      int // Rows read, valid or not
+	Published int
+	Rejected  []*domain.RowError
+}
+
+// Importer onboards a roster of creators, e.g. an agency's, in one go. Unlike ScraperService
+// it stops at the end of the source and reports every row it could not take.
+type Importer struct {
+	source    domain.ProfileSource
+	fetcher   domain.AvatarFetcher
+	storage   domain.AvatarStorage
+	publisher domain.EventPublisher
+	cfg       ImportConfig
+}
+
+func NewImporter(source domain.ProfileSource, fetcher domain.AvatarFetcher, storage domain.AvatarStorage, publisher domain.EventPublisher, cfg ImportConfig) *Importer {
+	return &Importer{
+		source:    source,
+		fetcher:   fetcher,
+		storage:   storage,
+		publisher: publisher,
+		cfg:       cfg,
+	}
+}
+
+// Run imports every row of the source. Invalid rows and avatars that cannot be fetched or
+// stored are rejected and the import goes on; failing to read the source or to publish stops
+// it, and the report tells how far it got.
+func (im *Importer) Run(ctx context.Context) (*ImportReport, error) {
+	report := &ImportReport{}
+
+	var interval time.Duration
+	if im.cfg.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / im.cfg.Rate)
+	}
+	var lastPublish time.Time
+
+	for {
+		// 1. Read and validate
+		discovered, err := im.source.Next(ctx)
+		var rowErr *domain.RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			return report, nil
+		case errors.As(err, &rowErr):
+			report.Rows++
+			report.Rejected = append(report.Rejected, rowErr)
+			continue
+		case err != nil:
+			return report, err
+		}
+		report.Rows++
+
+		// 2. Store the avatar, the row points to an image rather than a hosted URL
+		profile := discovered.Influencer
+		if profile.AvatarURL != "" {
+			url, err := im.storeAvatar(ctx, profile.Username, profile.AvatarURL)
+			if err != nil {
+				report.Rejected = append(report.Rejected, &domain.RowError{Row: discovered.Row, Err: err})
+				continue
+			}
+			profile.AvatarURL = url
+		}
+		if im.cfg.DryRun {
+			continue
+		}
+
+		// 3. Publish, no faster than the configured rate
+		if wait := interval - time.Since(lastPublish); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return report, ctx.Err()
+			}
+		}
+		lastPublish = time.Now()
+		if err := im.publisher.PublishProfile(ctx, profile); err != nil {
+			return report, fmt.Errorf("publishing row %d: %w", discovered.Row, err)
+		}
+		report.Published++
+	}
+}
+
+// storeAvatar fetches the image and uploads it, returning its URL. A dry run only fetches it.
+func (im *Importer) storeAvatar(ctx context.Context, username, location string) (string, error) {
+	image, err := im.fetcher.FetchAvatar(ctx, location)
+	if err != nil {
+		return "", fmt.Errorf("avatar: %w", err)
+	}
+	if im.cfg.DryRun {
+		return location, nil
+	}
+	url, err := im.storage.UploadAvatar(ctx, username, image)
+	if err != nil {
+		return "", fmt.Errorf("avatar upload: %w", err)
+	}
+	return url, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hammo/influScope/pkg/models"
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+// mockFetcher serves the avatars it knows, by location
+type mockFetcher struct {
+	images map[string]string
+}
+
+func (m *mockFetcher) FetchAvatar(ctx context.Context, location string) ([]byte, error) {
+	image, ok := m.images[location]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []byte(image), nil
+}
+
+func roster() *stubSource {
+	return &stubSource{results: []stubResult{
+		{profile: domain.DiscoveredProfile{Row: 2, Influencer: models.Influencer{Username: "alice", AvatarURL: "avatars/alice.jpg"}}},
+		{err: &domain.RowError{Row: 3, Err: errors.New("username is required")}},
+		{profile: domain.DiscoveredProfile{Row: 4, Influencer: models.Influencer{Username: "bob", AvatarURL: "https://cdn/missing.jpg"}}},
+		{profile: domain.DiscoveredProfile{Row: 5, Influencer: models.Influencer{Username: "carol"}}},
+	}}
+}
+
+func TestImportPublishesValidRows(t *testing.T) {
+	storage := &mockStorage{}
+	publisher := &mockPublisher{}
+	fetcher := &mockFetcher{images: map[string]string{"avatars/alice.jpg": "jpeg"}}
+
+	report, err := NewImporter(roster(), fetcher, storage, publisher, ImportConfig{}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if report.Rows != 4 || report.Published != 2 || len(publisher.published) != 2 {
+		t.Fatalf("Expected 2 of 4 rows published, got %+v", report)
+	}
+	if publisher.published[0].AvatarURL != "http://s3/avatars/alice.jpg" || len(storage.uploads) != 1 {
+		t.Errorf("Expected alice's avatar to be uploaded, got %s", publisher.published[0].AvatarURL)
+	}
+	if publisher.published[1].Username != "carol" || publisher.published[1].AvatarURL != "" {
+		t.Errorf("Expected carol without an avatar, got %+v", publisher.published[1])
+	}
+
+	// The invalid row and the missing avatar are both reported with their row
+	if len(report.Rejected) != 2 || report.Rejected[0].Row != 3 || report.Rejected[1].Row != 4 {
+		t.Fatalf("Expected rows 3 and 4 to be rejected, got %v", report.Rejected)
+	}
+	if !strings.Contains(report.Rejected[1].Error(), "avatar") {
+		t.Errorf("Expected the avatar failure to be explained, got %v", report.Rejected[1])
+	}
+}
+
+func TestImportDryRunPublishesNothing(t *testing.T) {
+	storage := &mockStorage{}
+	publisher := &mockPublisher{}
+	fetcher := &mockFetcher{images: map[string]string{"avatars/alice.jpg": "jpeg"}}
+
+	report, err := NewImporter(roster(), fetcher, storage, publisher, ImportConfig{DryRun: true}).Run(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(publisher.published) != 0 || len(storage.uploads) != 0 || report.Published != 0 {
+		t.Errorf("Expected no upload or publish, got %d uploads, %d published", len(storage.uploads), len(publisher.published))
+	}
+	// Avatars are still fetched, so a missing one shows up before the real import
+	if len(report.Rejected) != 2 {
+		t.Errorf("Expected the same 2 rejected rows, got %v", report.Rejected)
+	}
+}
+
+func TestImportIsRateLimited(t *testing.T) {
+	source := &stubSource{}
+	for i := 0; i < 3; i++ {
+		source.results = append(source.results, stubResult{profile: domain.DiscoveredProfile{Influencer: models.Influencer{Username: "user"}}})
+	}
+	publisher := &mockPublisher{}
+
+	// 20 profiles/s: 50ms between two publishes
+	start := time.Now()
+	if _, err := NewImporter(source, &mockFetcher{}, &mockStorage{}, publisher, ImportConfig{Rate: 20}).Run(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); len(publisher.published) != 3 || elapsed < 100*time.Millisecond {
+		t.Errorf("Expected 3 publishes spread over at least 100ms, got %d in %v", len(publisher.published), elapsed)
+	}
+}
@@ -2,87 +2,92 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"io"
 	"log"
-	"math/rand"
 	"time"
 
-	"github.com/brianvoe/gofakeit/v6"
-	"github.com/hammo/influScope/pkg/models"
 	"github.com/hammo/influScope/scraper/internal/domain"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-var (
-	categories  = []string{"Tech", "Fashion", "Travel", "Food", "Gaming"}
-	bioKeywords = map[string][]string{
-		"Tech":    {"gadgets", "coding", "AI", "golang", "developer"},
-		"Fashion": {"style", "OOTD", "luxury", "vogue", "streetwear"},
-		"Travel":  {"wanderlust", "adventure", "nomad", "exploring"},
-		"Food":    {"vegan", "tasty", "recipes", "organic", "chef"},
-		"Gaming":  {"esports", "twitch", "fortnite", "streamer"},
-	}
-)
-
 type ScraperService struct {
+	source    domain.ProfileSource
 	storage   domain.AvatarStorage
 	publisher domain.EventPublisher
 	metric    prometheus.Counter
+	interval  time.Duration // Pause between two published profiles
 }
 
-func NewScraperService(storage domain.AvatarStorage, publisher domain.EventPublisher, metric prometheus.Counter) *ScraperService {
+func NewScraperService(source domain.ProfileSource, storage domain.AvatarStorage, publisher domain.EventPublisher, metric prometheus.Counter, interval time.Duration) *ScraperService {
 	return &ScraperService{
+		source:    source,
 		storage:   storage,
 		publisher: publisher,
 		metric:    metric,
+		interval:  interval,
 	}
 }
 
-// GenerateSmartProfile is now exported so it can be tested easily
-func (s *ScraperService) GenerateSmartProfile() models.Influencer {
-	category := categories[rand.Intn(len(categories))]
-	keywords := bioKeywords[category]
-	keyword := keywords[rand.Intn(len(keywords))]
-
-	return models.Influencer{
-		ID:             gofakeit.UUID(),
-		Username:       gofakeit.Username(),
-		Platform:       gofakeit.RandomString([]string{"Instagram", "TikTok", "YouTube"}),
-		Followers:      gofakeit.Number(1000, 5000000),
-		Category:       category,
-		Bio:            fmt.Sprintf("%s | Loves %s | #%s", gofakeit.JobDescriptor(), keyword, category),
-		EngagementRate: float64(gofakeit.Number(10, 80)) / 10.0,
-	}
-}
-
-// Run executes the continuous scraping loop
+// Run publishes profiles from the source, one every interval, until ctx is cancelled or a
+// finite source is exhausted. Invalid profiles and source failures are logged and skipped.
 func (s *ScraperService) Run(ctx context.Context) {
-	log.Println("Scraper Service Started! Generating profiles...")
+	log.Println("Scraper Service Started! Reading profiles...")
 
 	for {
-		select {
-		case <-ctx.Done():
+		discovered, err := s.source.Next(ctx)
+		switch {
+		case ctx.Err() != nil:
 			log.Println("Scraper service stopping...")
 			return
-		default:
-			profile := s.GenerateSmartProfile()
-			dummyImage := []byte(fmt.Sprintf("Fake image content for %s", profile.Username))
+		case errors.Is(err, io.EOF):
+			log.Println("Profile source exhausted, nothing left to publish")
+			return
+		case err != nil:
+			var rowErr *domain.RowError
+			if errors.As(err, &rowErr) {
+				log.Printf("Skipping invalid profile: %v", err)
+				continue
+			}
+			// The source itself failed, e.g. the endpoint is down: try again after a pause
+			log.Printf("Profile source failed: %v", err)
+			if !s.wait(ctx) {
+				log.Println("Scraper service stopping...")
+				return
+			}
+			continue
+		}
 
-			url, err := s.storage.UploadAvatar(ctx, profile.Username, dummyImage)
+		profile := discovered.Influencer
+		if len(discovered.Avatar) > 0 {
+			url, err := s.storage.UploadAvatar(ctx, profile.Username, discovered.Avatar)
 			if err != nil {
 				log.Printf("Avatar upload failed: %v", err)
 				continue
 			}
 			profile.AvatarURL = url
+		}
 
-			if err := s.publisher.PublishProfile(ctx, profile); err != nil {
-				log.Printf("Failed to publish profile: %v", err)
-			} else {
-				log.Printf("Discovered: %-15s | %s", profile.Username, profile.Category)
-				s.metric.Inc()
-			}
+		if err := s.publisher.PublishProfile(ctx, profile); err != nil {
+			log.Printf("Failed to publish profile: %v", err)
+		} else {
+			log.Printf("Discovered: %-15s | %s", profile.Username, profile.Category)
+			s.metric.Inc()
+		}
 
-			time.Sleep(1 * time.Second)
+		if !s.wait(ctx) {
+			log.Println("Scraper service stopping...")
+			return
 		}
 	}
 }
+
+// wait paces the loop, it returns false when ctx is cancelled meanwhile
+func (s *ScraperService) wait(ctx context.Context) bool {
+	select {
+	case <-time.After(s.interval):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
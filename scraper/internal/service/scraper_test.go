@@ -1,17 +1,102 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"io"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/hammo/influScope/pkg/models"
+	"github.com/hammo/influScope/scraper/internal/domain"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func TestGenerateSmartProfile(t *testing.T) {
-	// Initialize service with nil dependencies since we only test generation logic
+// --- MOCKS ---
+
+// stubSource replays a fixed list of results, then reports the end of the source
+type stubSource struct {
+	results []stubResult
+}
+
+type stubResult struct {
+	profile domain.DiscoveredProfile
+	err     error
+}
+
+func (s *stubSource) Next(ctx context.Context) (domain.DiscoveredProfile, error) {
+	if len(s.results) == 0 {
+		return domain.DiscoveredProfile{}, io.EOF
+	}
+	r := s.results[0]
+	s.results = s.results[1:]
+	return r.profile, r.err
+}
+func (s *stubSource) Close() error { return nil }
+
+type mockStorage struct {
+	uploads []string
+}
+
+func (m *mockStorage) UploadAvatar(ctx context.Context, username string, imageData []byte) (string, error) {
+	m.uploads = append(m.uploads, username)
+	return "http://s3/avatars/" + username + ".jpg", nil
+}
+
+type mockPublisher struct {
+	mu        sync.Mutex
+	published []models.Influencer
+}
+
+func (m *mockPublisher) PublishProfile(ctx context.Context, profile models.Influencer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.published = append(m.published, profile)
+	return nil
+}
+func (m *mockPublisher) Close() error { return nil }
+
+// --- TESTS ---
+
+func TestRunPublishesFromSource(t *testing.T) {
+	source := &stubSource{results: []stubResult{
+		{profile: domain.DiscoveredProfile{Influencer: models.Influencer{Username: "generated"}, Avatar: []byte("img")}},
+		{err: &domain.RowError{Row: 2, Err: errors.New("username is required")}},
+		{profile: domain.DiscoveredProfile{Influencer: models.Influencer{Username: "imported", AvatarURL: "https://cdn/imported.jpg"}}},
+	}}
+	storage := &mockStorage{}
+	publisher := &mockPublisher{}
 	metric := prometheus.NewCounter(prometheus.CounterOpts{Name: "test"})
-	svc := NewScraperService(nil, nil, metric)
 
+	// Returns by itself once the source is exhausted
+	NewScraperService(source, storage, publisher, metric, 0).Run(context.Background())
+
+	if len(publisher.published) != 2 {
+		t.Fatalf("Expected the 2 valid profiles to be published, got %d", len(publisher.published))
+	}
+	if len(storage.uploads) != 1 || publisher.published[0].AvatarURL != "http://s3/avatars/generated.jpg" {
+		t.Errorf("Expected only the raw avatar to be uploaded, got %v / %s", storage.uploads, publisher.published[0].AvatarURL)
+	}
+	if publisher.published[1].AvatarURL != "https://cdn/imported.jpg" {
+		t.Errorf("Expected the source's avatar URL to be kept, got %s", publisher.published[1].AvatarURL)
+	}
+}
+
+func TestSyntheticSource(t *testing.T) {
+	discovered, err := NewSyntheticSource().Next(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if discovered.Influencer.Username == "" || len(discovered.Avatar) == 0 {
+		t.Errorf("Expected a generated profile with an avatar, got %+v", discovered)
+	}
+	if err := discovered.Influencer.Validate(); err != nil {
+		t.Errorf("Expected a valid profile, got %v", err)
+	}
+}
+
+func TestGenerateSmartProfile(t *testing.T) {
 	tests := []struct {
 		name          string
 		iterations    int
@@ -39,10 +124,10 @@ func TestGenerateSmartProfile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			for i := 0; i < tt.iterations; i++ {
-				profile := svc.GenerateSmartProfile()
+				profile := GenerateSmartProfile()
 
 				if tt.checkPlatform {
-					if profile.ID == "" || profile.Username == "" || profile.Bio == "" {
+					if profile.ID == "" || profile.Username == "" || profile.Bio == "" || profile.ScrapedAt.IsZero() {
 						t.Error("Expected required fields to be populated")
 					}
 					valid := map[string]bool{"Instagram": true, "TikTok": true, "YouTube": true}
@@ -65,6 +150,19 @@ func TestGenerateSmartProfile(t *testing.T) {
 					if profile.EngagementRate < 1.0 || profile.EngagementRate > 8.0 {
 						t.Errorf("Engagement Rate %.2f out of bounds", profile.EngagementRate)
 					}
+					if profile.Posts < 5 || profile.Posts > 30 || profile.Likes <= 0 {
+						t.Errorf("Activity out of bounds: %d posts, %d likes", profile.Posts, profile.Likes)
+					}
+					if len(profile.FollowerHistory) != historyDays || profile.FollowerHistory[historyDays-1] != profile.Followers {
+						t.Errorf("Follower history %v should end at today's %d followers", profile.FollowerHistory, profile.Followers)
+					}
+					if len(profile.PostInteractions) != profile.Posts {
+						t.Errorf("Expected interactions for each of the %d posts, got %d", profile.Posts, len(profile.PostInteractions))
+					}
+					video := profile.Platform == "TikTok" || profile.Platform == "YouTube"
+					if video != (profile.Views > 0) {
+						t.Errorf("Views %d do not match platform %s", profile.Views, profile.Platform)
+					}
 				}
 			}
 		})
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v6"
+	"github.com/hammo/influScope/pkg/models"
+	"github.com/hammo/influScope/scraper/internal/domain"
+)
+
+var (
+	categories  = []string{"Tech", "Fashion", "Travel", "Food", "Gaming"}
+	bioKeywords = map[string][]string{
+		"Tech":    {"gadgets", "coding", "AI", "golang", "developer"},
+		"Fashion": {"style", "OOTD", "luxury", "vogue", "streetwear"},
+		"Travel":  {"wanderlust", "adventure", "nomad", "exploring"},
+		"Food":    {"vegan", "tasty", "recipes", "organic", "chef"},
+		"Gaming":  {"esports", "twitch", "fortnite", "streamer"},
+	}
+)
+
+// syntheticSource generates fake profiles endlessly, for demos and load tests
+type syntheticSource struct{}
+
+func NewSyntheticSource() *syntheticSource {
+	return &syntheticSource{}
+}
+
+func (syntheticSource) Next(ctx context.Context) (domain.DiscoveredProfile, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.DiscoveredProfile{}, err
+	}
+	profile := GenerateSmartProfile()
+	return domain.DiscoveredProfile{
+		Influencer: profile,
+		Avatar:     []byte(fmt.Sprintf("Fake image content for %s", profile.Username)),
+	}, nil
+}
+
+func (syntheticSource) Close() error { return nil }
+
+// GenerateSmartProfile makes up a plausible profile, with bio keywords matching its category
+func GenerateSmartProfile() models.Influencer {
+	category := categories[rand.Intn(len(categories))]
+	keywords := bioKeywords[category]
+	keyword := keywords[rand.Intn(len(keywords))]
+
+	profile := models.Influencer{
+		ID:             gofakeit.UUID(),
+		Username:       gofakeit.Username(),
+		Platform:       gofakeit.RandomString([]string{"Instagram", "TikTok", "YouTube"}),
+		Followers:      gofakeit.Number(1000, 5000000),
+		Category:       category,
+		Bio:            fmt.Sprintf("%s | Loves %s | #%s", gofakeit.JobDescriptor(), keyword, category),
+		EngagementRate: float64(gofakeit.Number(10, 80)) / 10.0,
+		ScrapedAt:      time.Now().UTC(),
+	}
+	generateActivity(&profile)
+	generateAudience(&profile)
+	return profile
+}
+
+// generateActivity fills in recent post activity matching the profile's engagement rate,
+// so the analytics model lands close to what the scraper observed
+func generateActivity(p *models.Influencer) {
+	p.Posts = gofakeit.Number(5, 30)
+
+	// Video platforms are scored per view, the rest per follower
+	audience := float64(p.Followers) * float64(p.Posts)
+	if p.Platform == "TikTok" || p.Platform == "YouTube" {
+		p.Views = int64(audience * float64(gofakeit.Number(20, 150)) / 100)
+		audience = float64(p.Views)
+	}
+
+	interactions := audience * p.EngagementRate / 100
+	p.Likes = int64(interactions * 0.9)
+	p.Comments = int64(interactions * 0.08)
+	p.Shares = int64(interactions * 0.02)
+}
+
+// historyDays is how many daily follower counts a profile reports
+const historyDays = 7
+
+// generateAudience fills in the audience signals. About one profile in ten gets a bought
+// audience: a follower spike and interactions piled onto a single post.
+func generateAudience(p *models.Influencer) {
+	bought := gofakeit.Number(1, 10) == 1
+
+	p.Following = gofakeit.Number(50, 2000)
+
+	// Walk back from today's count with a small daily growth
+	p.FollowerHistory = make([]int, historyDays)
+	count := float64(p.Followers)
+	for day := historyDays - 1; day >= 0; day-- {
+		p.FollowerHistory[day] = int(count)
+		growth := float64(gofakeit.Number(0, 20)) / 1000
+		if bought && day == historyDays-1 {
+			growth = float64(gofakeit.Number(50, 300)) / 100
+		}
+		count /= 1 + growth
+	}
+
+	// Spread the interactions over the posts, around the average
+	total := p.Likes + p.Comments + p.Shares
+	p.PostInteractions = make([]int64, p.Posts)
+	for i := range p.PostInteractions {
+		p.PostInteractions[i] = total / int64(p.Posts) * int64(gofakeit.Number(70, 130)) / 100
+	}
+	if bought {
+		for i := range p.PostInteractions {
+			p.PostInteractions[i] /= 20
+		}
+		p.PostInteractions[0] = total
+	}
+}